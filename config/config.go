@@ -0,0 +1,299 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"merch-store/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Режимы работы: в dev разрешены небезопасные значения по умолчанию
+const (
+	ModeDev        = "dev"
+	ModeProduction = "production"
+)
+
+// Хранилища данных: PostgreSQL, файл SQLite или память процесса (данные теряются при перезапуске)
+const (
+	DriverPostgres = "postgres"
+	DriverSqlite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// DefaultJWTSecret - ключ подписи JWT по умолчанию, допустим только в режиме dev
+const DefaultJWTSecret = "super-secret-key"
+
+// Config - настройки сервиса. Значения по умолчанию переопределяются YAML-файлом из CONFIG_FILE,
+// а затем переменными окружения.
+type Config struct {
+	Mode       string         `yaml:"mode"`
+	ListenAddr string         `yaml:"listen_addr"`
+	Database   DatabaseConfig `yaml:"database"`
+	JWT        JWTConfig      `yaml:"jwt"`
+
+	StartingCoins     int           `yaml:"starting_coins"`
+	OrderCancelWindow time.Duration `yaml:"order_cancel_window"`
+
+	Reconcile   ReconcileConfig   `yaml:"reconcile"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+
+	// Catalog - товары, которые добавляются в каталог при запуске, если их еще нет
+	Catalog []models.Item `yaml:"catalog"`
+}
+
+// DatabaseConfig - выбор хранилища, путь к файлу SQLite и подключение к Postgres:
+// DSN целиком или по отдельным параметрам
+type DatabaseConfig struct {
+	Driver   string `yaml:"driver"`
+	Path     string `yaml:"path"`
+	DSN      string `yaml:"dsn"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// JWTConfig - подпись и срок действия токенов
+type JWTConfig struct {
+	Secret string        `yaml:"secret"`
+	TTL    time.Duration `yaml:"ttl"`
+}
+
+// ReconcileConfig - сверка балансов по расписанию, Interval == 0 отключает ее
+type ReconcileConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Adjust   bool          `yaml:"adjust"`
+}
+
+// IdempotencyConfig - ключи идемпотентности: через ReservationTimeout незавершенный запрос
+// считается брошенным и ключ можно занять заново, через TTL ключ удаляется
+type IdempotencyConfig struct {
+	ReservationTimeout time.Duration `yaml:"reservation_timeout"`
+	TTL                time.Duration `yaml:"ttl"`
+}
+
+// Default - настройки по умолчанию, совпадающие с docker-compose
+func Default() Config {
+	return Config{
+		Mode:       ModeProduction,
+		ListenAddr: ":8080",
+		Database: DatabaseConfig{
+			Driver:   DriverPostgres,
+			Path:     "merch-store.db",
+			Host:     "localhost",
+			Port:     5431,
+			User:     "postgres",
+			Password: "password",
+			Name:     "shop",
+			SSLMode:  "disable",
+		},
+		JWT: JWTConfig{
+			Secret: DefaultJWTSecret,
+			TTL:    24 * time.Hour,
+		},
+		StartingCoins:     1000,
+		OrderCancelWindow: 24 * time.Hour,
+		Idempotency: IdempotencyConfig{
+			ReservationTimeout: time.Minute,
+			TTL:                24 * time.Hour,
+		},
+		Catalog: []models.Item{
+			{Name: "t-shirt", Price: 80}, {Name: "cup", Price: 20}, {Name: "book", Price: 50},
+			{Name: "pen", Price: 10}, {Name: "powerbank", Price: 200}, {Name: "hoody", Price: 300},
+			{Name: "umbrella", Price: 200}, {Name: "socks", Price: 10}, {Name: "wallet", Price: 50},
+			{Name: "pink-hoody", Price: 500},
+		},
+	}
+}
+
+// Load - загрузка настроек: значения по умолчанию, затем YAML-файл из CONFIG_FILE, затем переменные окружения.
+// Настройки проверяются целиком, как для запуска API.
+func Load() (Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Read - загрузка настроек без проверки; подкоманды проверяют только нужные им части
+func Read() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv - переопределение настроек переменными окружения
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	texts := map[string]*string{
+		"APP_MODE":     &c.Mode,
+		"LISTEN_ADDR":  &c.ListenAddr,
+		"DB_DRIVER":    &c.Database.Driver,
+		"DB_PATH":      &c.Database.Path,
+		"DATABASE_URL": &c.Database.DSN,
+		"DB_HOST":      &c.Database.Host,
+		"DB_USER":      &c.Database.User,
+		"DB_PASSWORD":  &c.Database.Password,
+		"DB_NAME":      &c.Database.Name,
+		"DB_SSLMODE":   &c.Database.SSLMode,
+		"JWT_SECRET":   &c.JWT.Secret,
+	}
+	for name, target := range texts {
+		if value, ok := lookup(name); ok {
+			*target = value
+		}
+	}
+
+	ints := map[string]*int{
+		"DB_PORT":        &c.Database.Port,
+		"STARTING_COINS": &c.StartingCoins,
+	}
+	for name, target := range ints {
+		if value, ok := lookup(name); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("некорректное значение %s: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"JWT_TTL":             &c.JWT.TTL,
+		"ORDER_CANCEL_WINDOW": &c.OrderCancelWindow,
+		"RECONCILE_INTERVAL":  &c.Reconcile.Interval,
+
+		"IDEMPOTENCY_RESERVATION_TIMEOUT": &c.Idempotency.ReservationTimeout,
+		"IDEMPOTENCY_TTL":                 &c.Idempotency.TTL,
+	}
+	for name, target := range durations {
+		if value, ok := lookup(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("некорректное значение %s: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if value, ok := lookup("RECONCILE_ADJUST"); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("некорректное значение RECONCILE_ADJUST: %q", value)
+		}
+		c.Reconcile.Adjust = parsed
+	}
+
+	return nil
+}
+
+// Validate - проверка настроек при запуске; возвращает все найденные ошибки сразу
+func (c Config) Validate() error {
+	var errs []error
+	if c.Mode != ModeDev && c.Mode != ModeProduction {
+		errs = append(errs, fmt.Errorf("mode: ожидается %s или %s, получено %q", ModeDev, ModeProduction, c.Mode))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr: не задан адрес"))
+	}
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("jwt.secret: не задан ключ подписи"))
+	} else if c.JWT.Secret == DefaultJWTSecret && c.Mode != ModeDev {
+		errs = append(errs, errors.New("jwt.secret: ключ по умолчанию допустим только в режиме dev, задайте JWT_SECRET"))
+	}
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, errors.New("jwt.ttl: срок действия токена должен быть положительным"))
+	}
+	if c.StartingCoins < 0 {
+		errs = append(errs, errors.New("starting_coins: не может быть отрицательным"))
+	}
+	if c.OrderCancelWindow < 0 {
+		errs = append(errs, errors.New("order_cancel_window: не может быть отрицательным"))
+	}
+	if c.Reconcile.Interval < 0 {
+		errs = append(errs, errors.New("reconcile.interval: не может быть отрицательным"))
+	}
+	if c.Idempotency.ReservationTimeout <= 0 {
+		errs = append(errs, errors.New("idempotency.reservation_timeout: должен быть положительным"))
+	}
+	if c.Idempotency.TTL < c.Idempotency.ReservationTimeout {
+		errs = append(errs, errors.New("idempotency.ttl: не может быть меньше reservation_timeout"))
+	}
+
+	seen := make(map[string]bool, len(c.Catalog))
+	for i, item := range c.Catalog {
+		switch {
+		case strings.TrimSpace(item.Name) == "":
+			errs = append(errs, fmt.Errorf("catalog[%d]: не задано название", i))
+		case seen[item.Name]:
+			errs = append(errs, fmt.Errorf("catalog[%d]: товар %q указан дважды", i, item.Name))
+		case item.Price <= 0:
+			errs = append(errs, fmt.Errorf("catalog[%d]: цена товара %q должна быть положительной", i, item.Name))
+		case item.Stock != nil && *item.Stock < 0:
+			errs = append(errs, fmt.Errorf("catalog[%d]: остаток товара %q не может быть отрицательным", i, item.Name))
+		}
+		seen[item.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate - проверка настроек хранилища; их достаточно подкоманде migrate
+func (d DatabaseConfig) Validate() error {
+	var errs []error
+	switch d.Driver {
+	case DriverPostgres:
+		if d.DSN == "" && (d.Host == "" || d.Name == "" || d.User == "") {
+			errs = append(errs, errors.New("database: нужен dsn или host, name и user"))
+		}
+		if d.DSN == "" && (d.Port <= 0 || d.Port > 65535) {
+			errs = append(errs, fmt.Errorf("database.port: некорректный порт %d", d.Port))
+		}
+	case DriverSqlite:
+		if d.Path == "" {
+			errs = append(errs, errors.New("database.path: не задан путь к файлу базы данных"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.driver: ожидается %s, %s или %s, получено %q",
+			DriverPostgres, DriverSqlite, DriverMemory, d.Driver))
+	}
+	return errors.Join(errs...)
+}
+
+// ConnectionString - строка подключения к Postgres: DSN, если задан, иначе собирается из параметров
+func (d DatabaseConfig) ConnectionString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFileAndEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+mode: production
+listen_addr: ":9090"
+database:
+  host: db
+  port: 5432
+jwt:
+  secret: file-secret
+  ttl: 2h
+starting_coins: 500
+catalog:
+  - name: sticker
+    price: 5
+    stock: 100
+`), 0o600)
+	assert.NoError(t, err)
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("JWT_SECRET", "env-secret")
+	t.Setenv("DB_PORT", "6543")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.ListenAddr)
+	assert.Equal(t, "env-secret", cfg.JWT.Secret)
+	assert.Equal(t, 2*time.Hour, cfg.JWT.TTL)
+	assert.Equal(t, 500, cfg.StartingCoins)
+	assert.Equal(t, "host=db port=6543 user=postgres password=password dbname=shop sslmode=disable", cfg.Database.ConnectionString())
+	assert.Equal(t, 1, len(cfg.Catalog))
+	assert.Equal(t, 100, *cfg.Catalog[0].Stock)
+}
+
+func TestValidateDefaultSecret(t *testing.T) {
+	cfg := Default()
+	assert.ErrorContains(t, cfg.Validate(), "jwt.secret")
+
+	cfg.Mode = ModeDev
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.JWT.Secret = "secret"
+	cfg.JWT.TTL = 0
+	cfg.StartingCoins = -1
+	cfg.Catalog = append(cfg.Catalog, cfg.Catalog[0])
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "jwt.ttl")
+	assert.ErrorContains(t, err, "starting_coins")
+	assert.ErrorContains(t, err, "указан дважды")
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("APP_MODE", ModeDev)
+	t.Setenv("JWT_TTL", "day")
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "JWT_TTL")
+}
+
+func TestValidateDriver(t *testing.T) {
+	cfg := Default()
+	cfg.Mode = ModeDev
+	cfg.Database = DatabaseConfig{Driver: DriverMemory}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database = DatabaseConfig{Driver: DriverSqlite}
+	assert.ErrorContains(t, cfg.Validate(), "database.path")
+	cfg.Database.Path = "shop.db"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.Driver = "mysql"
+	assert.ErrorContains(t, cfg.Validate(), "database.driver")
+}
+
+func TestReadWithoutValidation(t *testing.T) {
+	t.Setenv("DB_DRIVER", DriverSqlite)
+	t.Setenv("DB_PATH", "shop.db")
+
+	// В режиме production ключ JWT по умолчанию не проходит полную проверку,
+	// но настройкам базы данных для migrate он не мешает
+	_, err := Load()
+	assert.ErrorContains(t, err, "jwt.secret")
+
+	cfg, err := Read()
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Database.Validate())
+	assert.Equal(t, "shop.db", cfg.Database.Path)
+}
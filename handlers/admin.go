@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateItemRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Price       int    `json:"price" binding:"required"`
+	Description string `json:"description"`
+	Stock       *int   `json:"stock"`
+}
+
+type RestockRequest struct {
+	Quantity int    `json:"quantity" binding:"required"`
+	Size     string `json:"size"`
+	Color    string `json:"color"`
+}
+
+type CreateVariantRequest struct {
+	Size  string `json:"size"`
+	Color string `json:"color"`
+	Stock *int   `json:"stock"`
+}
+
+type UpdateItemRequest struct {
+	Price       *int    `json:"price"`
+	Description *string `json:"description"`
+	Active      *bool   `json:"active"`
+}
+
+// CreateItem - добавление товара в каталог
+func (h *Handler) CreateItem(c *gin.Context) {
+	var req CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	item, err := h.services.Items.CreateItem(req.Name, req.Price, req.Description, req.Stock)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"description": message(c, "item_created"), "item": item})
+}
+
+// UpdateItem - изменение товара в каталоге
+func (h *Handler) UpdateItem(c *gin.Context) {
+	var req UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	item, err := h.services.Items.UpdateItem(c.Param("item"), req.Price, req.Description, req.Active)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "item_updated"), "item": item})
+}
+
+// DeactivateItem - снятие товара с продажи
+func (h *Handler) DeactivateItem(c *gin.Context) {
+	if err := h.services.Items.DeactivateItem(c.Param("item")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "item_deactivated")})
+}
+
+// RestockItem - пополнение склада
+func (h *Handler) RestockItem(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	var req RestockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	// Пополнение конкретного варианта товара
+	if req.Size != "" || req.Color != "" {
+		variant, err := h.services.Items.RestockVariant(c.Param("item"), req.Size, req.Color, req.Quantity, username.(string))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"description": message(c, "item_restocked"), "variant": variant})
+		return
+	}
+
+	item, err := h.services.Items.RestockItem(c.Param("item"), req.Quantity, username.(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "item_restocked"), "item": item})
+}
+
+// CreateVariant - добавление варианта товара (размер, цвет)
+func (h *Handler) CreateVariant(c *gin.Context) {
+	var req CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	variant, err := h.services.Items.CreateVariant(c.Param("item"), req.Size, req.Color, req.Stock)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"description": message(c, "variant_created"), "variant": variant})
+}
+
+// CheckLedger - сверка журнала проводок с балансами пользователей
+func (h *Handler) CheckLedger(c *gin.Context) {
+	report, err := h.services.Ledger.CheckLedger()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema":      report,
+	})
+}
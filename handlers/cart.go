@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCart - содержимое корзины
+func (h *Handler) GetCart(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	cart, err := h.services.Carts.GetCart(username.(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema":      cart,
+	})
+}
+
+// AddToCart - добавление товара в корзину
+func (h *Handler) AddToCart(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	var req AddToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+	if errs := req.Validate(); len(errs) > 0 {
+		respondValidationErrors(c, errs)
+		return
+	}
+
+	line, err := h.services.Carts.AddToCart(username.(string), req.Item, req.Amount, req.Size, req.Color)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "cart_item_added"), "item": line})
+}
+
+// RemoveFromCart - удаление позиции из корзины
+func (h *Handler) RemoveFromCart(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	cartItemID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	if err := h.services.Carts.RemoveFromCart(username.(string), uint(cartItemID)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "cart_item_removed")})
+}
+
+// Checkout - оформление всей корзины одной покупкой
+func (h *Handler) Checkout(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	orders, err := h.servicesFor(c).Carts.Checkout(username.(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "order_placed"), "orders": orders})
+}
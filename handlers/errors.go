@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"merch-store/services"
+	"merch-store/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Коды ошибок, которые не относятся к бизнес-правилам сервисов
+const (
+	codeInvalidRequest  = "invalid_request"
+	codeValidationError = "validation_error"
+	codeInternalError   = "internal_error"
+	codeInvalidMaxPrice = "invalid_max_price"
+
+	codeRecipientRequired = "recipient_required"
+	codeItemRequired      = "item_required"
+)
+
+// errorStatuses - HTTP-статусы для ошибок бизнес-правил, по умолчанию 400
+var errorStatuses = map[*services.Error]int{
+	services.ErrUserNotFound:             http.StatusNotFound,
+	services.ErrUserExists:               http.StatusConflict,
+	services.ErrUnauthorized:             http.StatusUnauthorized,
+	services.ErrRecipientNotFound:        http.StatusNotFound,
+	services.ErrItemNotFound:             http.StatusNotFound,
+	services.ErrItemExists:               http.StatusConflict,
+	services.ErrOutOfStock:               http.StatusConflict,
+	services.ErrVariantNotFound:          http.StatusNotFound,
+	services.ErrVariantExists:            http.StatusConflict,
+	services.ErrOrderNotFound:            http.StatusNotFound,
+	services.ErrOrderNotCancellable:      http.StatusConflict,
+	services.ErrCancelWindowExpired:      http.StatusConflict,
+	services.ErrInvalidTransition:        http.StatusConflict,
+	services.ErrCartItemNotFound:         http.StatusNotFound,
+	services.ErrCartChanged:              http.StatusConflict,
+	services.ErrIdempotencyKeyReused:     http.StatusUnprocessableEntity,
+	services.ErrIdempotencyKeyInProgress: http.StatusConflict,
+	services.ErrIdempotencyKeyCommitted:  http.StatusConflict,
+}
+
+// ErrorStatus - HTTP-статус и код ошибки; ошибки без кода считаются внутренними
+func ErrorStatus(err error) (int, string) {
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) {
+		return http.StatusInternalServerError, codeInternalError
+	}
+	if status, ok := errorStatuses[domainErr]; ok {
+		return status, domainErr.Code
+	}
+	return http.StatusBadRequest, domainErr.Code
+}
+
+// language - язык сообщений по заголовку Accept-Language
+func language(c *gin.Context) string {
+	lang := utils.Language(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	return lang
+}
+
+// message - текст успешного ответа по коду на языке клиента
+func message(c *gin.Context, code string) string {
+	return utils.Message(language(c), code, code)
+}
+
+// respondError - ответ на ошибку сервиса с HTTP-статусом, кодом ошибки и текстом на языке клиента
+func respondError(c *gin.Context, err error) {
+	status, code := ErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Request.Method, c.FullPath(), err)
+		respondCode(c, status, code)
+		return
+	}
+	c.JSON(status, gin.H{"code": code, "description": utils.Message(language(c), code, err.Error())})
+}
+
+// respondCode - ответ с кодом ошибки и текстом из каталога сообщений
+func respondCode(c *gin.Context, status int, code string) {
+	c.JSON(status, gin.H{"code": code, "description": utils.Message(language(c), code, code)})
+}
+
+// respondInvalidRequest - ответ на запрос, который не удалось разобрать
+func respondInvalidRequest(c *gin.Context) {
+	respondCode(c, http.StatusBadRequest, codeInvalidRequest)
+}
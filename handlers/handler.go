@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"merch-store/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler - HTTP-обработчики API поверх сервисов магазина
+type Handler struct {
+	services *services.Services
+}
+
+// New - создание обработчиков для сервисов svc
+func New(svc *services.Services) *Handler {
+	return &Handler{services: svc}
+}
+
+// servicesFor - сервисы для запроса c: если IdempotencyMiddleware зарезервировал ключ идемпотентности,
+// бизнес-операция фиксирует его в своей транзакции
+func (h *Handler) servicesFor(c *gin.Context) *services.Services {
+	if request, ok := c.Get("idempotentRequest"); ok {
+		return h.services.WithIdempotentRequest(request.(*services.IdempotentRequest))
+	}
+	return h.services
+}
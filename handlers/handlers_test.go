@@ -2,143 +2,497 @@ package handlers
 
 import (
 	"bytes"
+	"database/sql"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"merch-store/middlewares"
+	"merch-store/models"
 	"merch-store/repositories"
+	"merch-store/services"
 )
 
-func setupMockDB() (*sqlx.DB, sqlmock.Sqlmock) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		panic(err)
+// setupHandler - обработчики поверх хранилища в памяти с небольшим каталогом и пользователями
+func setupHandler(t *testing.T, users ...string) (*Handler, repositories.Store) {
+	store := repositories.NewMemoryStore()
+	return setupHandlerWithStore(t, store, users...), store
+}
+
+// setupHandlerWithStore - обработчики поверх переданного хранилища с небольшим каталогом и пользователями
+func setupHandlerWithStore(t *testing.T, store repositories.Store, users ...string) *Handler {
+	hoodyStock := 1
+	err := store.Items().SeedItems([]models.Item{
+		{Name: "t-shirt", Price: 80, Active: true},
+		{Name: "cup", Price: 20, Active: true},
+		{Name: "pen", Price: 10, Active: true},
+		{Name: "umbrella", Price: 200, Active: true},
+		{Name: "hoody", Price: 300, Active: true, Stock: &hoodyStock},
+	})
+	assert.NoError(t, err)
+
+	h := New(services.New(store, services.DefaultOptions()))
+	for _, name := range users {
+		assert.NoError(t, h.services.Users.RegisterUser(name, "password123"))
 	}
-	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	return sqlxDB, mock
+	return h
+}
+
+// commitFailureStore - хранилище, в котором фиксация любой транзакции завершается ошибкой
+type commitFailureStore struct {
+	repositories.Store
+}
+
+func (s commitFailureStore) Atomic(fn func(tx repositories.Store) error) error {
+	return s.Store.Atomic(func(tx repositories.Store) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return sql.ErrConnDone
+	})
 }
 
 func TestSendCoinHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+	h, store := setupHandler(t, "user1", "user2")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(1000))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+
+	requestBody := bytes.NewBufferString(`{"toUser": "user2", "amount": 100, "message": "thanks for the release help!"}`)
+	c.Request, _ = http.NewRequest("POST", "/sendCoin", requestBody)
+	c.Request.Header.Set("Content-Type", "application/json")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE name=$1")).
-		WithArgs("user2").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	h.SendCoin(c)
 
-	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins - $1 WHERE name = $2")).
-		WithArgs(100, "user1").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"transactionId":1`)
 
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins + $1 WHERE name = $2")).
-		WithArgs(100, "user2").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	recipient, err := store.Users().GetUserByName("user2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1100, recipient.Coins)
+}
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO transactions")).
-		WithArgs("user1", "user2", 100).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+func TestBuyItemHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
 
-	mock.ExpectCommit()
+	h, store := setupHandler(t, "user1")
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "user1")
+	c.Params = append(c.Params, gin.Param{Key: "item", Value: "t-shirt"})
 
-	requestBody := bytes.NewBufferString(`{"toUser": "user2", "amount": 100}`)
-	c.Request, _ = http.NewRequest("POST", "/sendCoin", requestBody)
+	requestBody := bytes.NewBufferString(`{"amount": 2}`)
+	c.Request, _ = http.NewRequest("POST", "/buy/t-shirt", requestBody)
 	c.Request.Header.Set("Content-Type", "application/json")
 
-	SendCoin(c)
+	h.BuyItem(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NoError(t, mock.ExpectationsWereMet())
+
+	user, err := store.Users().GetUserByName("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 840, user.Coins)
 }
 
-func TestBuyItemHandler(t *testing.T) {
+func TestGetUserInfoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1", "user2")
+
+	_, err := h.services.Coins.SendCoin("user2", "user1", 100, "thanks for the release help!")
+	assert.NoError(t, err)
+	_, err = h.services.Items.BuyItem("user1", "t-shirt", 2, "", "")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("GET", "/info", nil)
+
+	h.GetUserInfo(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"coins":940`)
+	assert.Contains(t, w.Body.String(), `"message":"thanks for the release help!"`)
+}
+
+func TestRegisterHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+	h, store := setupHandler(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	requestBody := bytes.NewBufferString(`{"username": "user1", "password": "password123"}`)
+	c.Request, _ = http.NewRequest("POST", "/register", requestBody)
+	c.Request.Header.Set("Content-Type", "application/json")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).AddRow(1, 1000))
+	h.Register(c)
 
-	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins - $1 WHERE name = $2")).
-		WithArgs(160, "user1").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.Equal(t, http.StatusOK, w.Code)
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO inventory")).
-		WithArgs(1, "t-shirt", 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	user, err := store.Users().GetUserByName("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, user.Coins)
+}
 
-	mock.ExpectCommit()
+func TestListItemsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/items?max_price=20&sort=price", nil)
+
+	h.ListItems(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `"name":"pen"`)
+	assert.NotContains(t, body, `"name":"t-shirt"`)
+	assert.Less(t, strings.Index(body, `"name":"pen"`), strings.Index(body, `"name":"cup"`))
+}
+
+func TestCreateItemHandlerConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	requestBody := bytes.NewBufferString(`{"name": "cup", "price": 30, "description": "Кружка с логотипом"}`)
+	c.Request, _ = http.NewRequest("POST", "/admin/items", requestBody)
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateItem(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeactivateItemHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, store := setupHandler(t)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "item", Value: "umbrella"})
+	c.Request, _ = http.NewRequest("DELETE", "/admin/items/umbrella", nil)
+
+	h.DeactivateItem(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, err := store.Items().GetActiveItem("umbrella")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "item", Value: "sword"})
+	c.Request, _ = http.NewRequest("DELETE", "/admin/items/sword", nil)
+
+	h.DeactivateItem(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBuyItemHandlerOutOfStock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, store := setupHandler(t, "user1")
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "user1")
-	c.Params = append(c.Params, gin.Param{Key: "item", Value: "t-shirt"})
+	c.Params = append(c.Params, gin.Param{Key: "item", Value: "hoody"})
 
 	requestBody := bytes.NewBufferString(`{"amount": 2}`)
-	c.Request, _ = http.NewRequest("POST", "/buy/t-shirt", requestBody)
+	c.Request, _ = http.NewRequest("POST", "/buy/hoody", requestBody)
 	c.Request.Header.Set("Content-Type", "application/json")
 
-	BuyItem(c)
+	h.BuyItem(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	user, err := store.Users().GetUserByName("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, user.Coins)
+}
+
+func TestGetOrdersHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1")
+
+	order, err := h.services.Items.BuyItem("user1", "cup", 3, "", "")
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("GET", "/orders", nil)
+
+	h.GetOrders(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"id":%d`, order.ID))
+	assert.Contains(t, w.Body.String(), `"total":60`)
+	assert.Contains(t, w.Body.String(), `"createdAt":"`+order.CreatedAt.Format(time.RFC3339Nano)+`"`)
 }
 
-func TestGetUserInfoHandler(t *testing.T) {
+func TestUpdateOrderStatusHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+	h, _ := setupHandler(t, "user1")
+
+	order, err := h.services.Items.BuyItem("user1", "cup", 3, "", "")
+	assert.NoError(t, err)
+	id := strconv.Itoa(int(order.ID))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: id})
+	c.Request, _ = http.NewRequest("PUT", "/admin/orders/"+id+"/status", bytes.NewBufferString(`{"status": "packed"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "coins"}).AddRow(1, "user1", 1000))
+	h.UpdateOrderStatus(c)
+	assert.Equal(t, http.StatusOK, w.Code)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT item_name, amount FROM inventory WHERE user_id=$1")).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"item_name", "amount"}).AddRow("t-shirt", 2))
+	// Нельзя выдать заказ, который еще не собран
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: id})
+	c.Request, _ = http.NewRequest("PUT", "/admin/orders/"+id+"/status", bytes.NewBufferString(`{"status": "delivered"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT from_user, to_user, amount FROM transactions WHERE from_user=$1 OR to_user=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"from_user", "to_user", "amount"}).AddRow("user2", "user1", 100))
+	h.UpdateOrderStatus(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCheckoutHandlerEmptyCart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1")
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("POST", "/cart/checkout", nil)
+
+	h.Checkout(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIdempotencyMiddlewareReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/sendCoin", func(c *gin.Context) {
+		c.Set("username", "user1")
+	}, middlewares.IdempotencyMiddleware(h.services.Idempotency), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"description": "Успешная передача монет."})
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Первый запрос выполняется и сохраняет ответ
+	first := send(`{"toUser": "user2", "amount": 100}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, 1, calls)
+
+	// Повтор возвращает сохраненный ответ без повторного выполнения
+	replay := send(`{"toUser": "user2", "amount": 100}`)
+	assert.Equal(t, http.StatusOK, replay.Code)
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// Тот же ключ с другим телом запроса
+	conflict := send(`{"toUser": "user2", "amount": 500}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, conflict.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddlewareCommitsKeyWithOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, store := setupHandler(t, "user1", "user2")
 
-	GetUserInfo(c)
+	var committedInHandler bool
+	router := gin.New()
+	router.POST("/sendCoin", func(c *gin.Context) {
+		c.Set("username", "user1")
+	}, middlewares.IdempotencyMiddleware(h.services.Idempotency), func(c *gin.Context) {
+		h.SendCoin(c)
+		// Ключ зафиксирован вместе с переводом, до сохранения ответа
+		record, err := store.IdempotencyKeys().GetIdempotencyKey("user1", "key-1")
+		committedInHandler = err == nil && record.CommittedAt != nil && record.StatusCode == nil
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(`{"toUser": "user2", "amount": 100}`))
+	req.Header.Set("Idempotency-Key", "key-1")
+	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, committedInHandler)
 }
 
-func TestRegisterHandler(t *testing.T) {
+func TestIdempotencyMiddlewareReleasesKeyOnPanic(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+	h, _ := setupHandler(t)
+
+	panics := true
+	calls := 0
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/sendCoin", func(c *gin.Context) {
+		c.Set("username", "user1")
+	}, middlewares.IdempotencyMiddleware(h.services.Idempotency), func(c *gin.Context) {
+		calls++
+		if panics {
+			panic("handler failure")
+		}
+		c.JSON(http.StatusOK, gin.H{"description": "Успешная передача монет."})
+	})
+
+	send := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(`{"toUser": "user2", "amount": 100}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Паника обработчика: Recovery отвечает 500, ключ освобождается
+	assert.Equal(t, http.StatusInternalServerError, send())
+
+	// Повтор с тем же ключом снова резервирует его и выполняется
+	panics = false
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, 2, calls)
+}
+
+func TestSendCoinHandlerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	send := func(h *Handler) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("username", "user1")
+		c.Request, _ = http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(`{"toUser": "user2", "amount": 100}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.SendCoin(c)
+		return w.Code
+	}
+
+	// Получатель не найден: списание откатывается
+	h, store := setupHandler(t, "user1")
+	assert.Equal(t, http.StatusNotFound, send(h))
+
+	user, err := store.Users().GetUserByName("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, user.Coins)
+
+	// Ошибка фиксации транзакции - это внутренняя ошибка, а не успешный перевод
+	memory := repositories.NewMemoryStore()
+	setupHandlerWithStore(t, memory, "user1", "user2")
+	h = New(services.New(commitFailureStore{memory}, services.DefaultOptions()))
+	assert.Equal(t, http.StatusInternalServerError, send(h))
+}
+
+func TestSendCoinHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, store := setupHandler(t, "user1", "user2")
+
+	cases := []struct {
+		body  string
+		field string
+	}{
+		{`{"toUser": "user2", "amount": -100}`, `"field":"amount"`},
+		{`{"toUser": "user2", "amount": 0}`, `"field":"amount"`},
+		{`{"toUser": "user1", "amount": 100}`, `"field":"toUser"`},
+		{`{"toUser": "", "amount": 100}`, `"field":"toUser"`},
+		{`{"toUser": "user2", "amount": 100, "message": "` + strings.Repeat("я", 201) + `"}`, `"field":"message"`},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("username", "user1")
+		c.Request, _ = http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(tc.body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.SendCoin(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		assert.Contains(t, w.Body.String(), tc.field, tc.body)
+	}
+
+	// Ни один из таких запросов не меняет балансы
+	user, err := store.Users().GetUserByName("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, user.Coins)
+}
+
+func TestQuantityValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1")
+
+	for _, amount := range []string{"0", "1001", "1844674407370955161"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("username", "user1")
+		c.Params = append(c.Params, gin.Param{Key: "item", Value: "pen"})
+		c.Request, _ = http.NewRequest("POST", "/buy/pen", bytes.NewBufferString(`{"amount": `+amount+`}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.BuyItem(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, amount)
+		assert.Contains(t, w.Body.String(), `"code":"invalid_quantity"`, amount)
+
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Set("username", "user1")
+		c.Request, _ = http.NewRequest("POST", "/cart", bytes.NewBufferString(`{"item": "pen", "amount": `+amount+`}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.AddToCart(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, amount)
+		assert.Contains(t, w.Body.String(), `"code":"invalid_quantity"`, amount)
+	}
+}
+
+func TestRegisterHandlerDuplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
-		WithArgs("user1", sqlmock.AnyArg(), 1000).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	h, _ := setupHandler(t, "user1")
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -147,8 +501,124 @@ func TestRegisterHandler(t *testing.T) {
 	c.Request, _ = http.NewRequest("POST", "/register", requestBody)
 	c.Request.Header.Set("Content-Type", "application/json")
 
-	Register(c)
+	h.Register(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"user_exists"`)
+}
+
+func TestErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{services.ErrRecipientNotFound, http.StatusNotFound, services.ErrRecipientNotFound.Code},
+		{services.ErrUserExists, http.StatusConflict, services.ErrUserExists.Code},
+		{fmt.Errorf("checkout: %w", services.ErrOutOfStock), http.StatusConflict, services.ErrOutOfStock.Code},
+		{services.ErrInsufficientFunds, http.StatusBadRequest, services.ErrInsufficientFunds.Code},
+		{sql.ErrConnDone, http.StatusInternalServerError, codeInternalError},
+	}
+
+	for _, tc := range cases {
+		status, code := ErrorStatus(tc.err)
+		assert.Equal(t, tc.status, status, tc.err.Error())
+		assert.Equal(t, tc.code, code, tc.err.Error())
+	}
+}
+
+func TestSendCoinHandlerLocalizedError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1", "user2")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("POST", "/sendCoin", bytes.NewBufferString(`{"toUser": "user2", "amount": 5000}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.8")
+
+	h.SendCoin(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "en", w.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"code": "insufficient_funds", "description": "insufficient coins"}`, w.Body.String())
+}
+
+func TestSuccessMessageLocalized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1")
+
+	for lang, expected := range map[string]string{"en": "Item removed from cart.", "ru": "Позиция удалена из корзины."} {
+		line, err := h.services.Carts.AddToCart("user1", "cup", 1, "", "")
+		assert.NoError(t, err)
+		id := strconv.Itoa(int(line.ID))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("username", "user1")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest("DELETE", "/cart/"+id, nil)
+		c.Request.Header.Set("Accept-Language", lang)
+
+		h.RemoveFromCart(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, lang, w.Header().Get("Content-Language"))
+		assert.JSONEq(t, `{"description": "`+expected+`"}`, w.Body.String())
+	}
+}
+
+func TestGetCoinHistoryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1", "user2")
+
+	_, err := h.services.Coins.SendCoin("user1", "user2", 100, "thanks!")
+	assert.NoError(t, err)
+	_, err = h.services.Coins.SendCoin("user2", "user1", 50, "")
+	assert.NoError(t, err)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("GET", "/history?direction=sent&from="+today+"&to="+today, nil)
+
+	h.GetCoinHistory(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Contains(t, w.Body.String(), `"direction":"sent"`)
+	assert.NotContains(t, w.Body.String(), `"direction":"received"`)
+	assert.Contains(t, w.Body.String(), `"message":"thanks!"`)
+	assert.NotContains(t, w.Body.String(), "nextCursor")
+
+	// Некорректная дата отклоняется
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("GET", "/history?from=yesterday", nil)
+
+	h.GetCoinHistory(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_date_range"`)
+}
+
+func TestGetUserInfoHandlerInvalidHistoryView(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h, _ := setupHandler(t, "user1")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("username", "user1")
+	c.Request, _ = http.NewRequest("GET", "/info?coinHistory=flat", nil)
+
+	h.GetUserInfo(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_history_view"`)
 }
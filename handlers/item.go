@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListItems - каталог товаров, доступных для покупки
+func (h *Handler) ListItems(c *gin.Context) {
+	maxPrice := 0
+	if value := c.Query("max_price"); value != "" {
+		var err error
+		maxPrice, err = strconv.Atoi(value)
+		if err != nil || maxPrice <= 0 {
+			respondCode(c, http.StatusBadRequest, codeInvalidMaxPrice)
+			return
+		}
+	}
+
+	items, err := h.services.Items.ListItems(maxPrice, c.Query("sort"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema": gin.H{
+			"items": items,
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrders - история заказов пользователя
+func (h *Handler) GetOrders(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	orders, err := h.services.Orders.ListOrders(username.(string))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema": gin.H{
+			"orders": orders,
+		},
+	})
+}
+
+// CancelOrder - отмена заказа с возвратом монет
+func (h *Handler) CancelOrder(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	refund, err := h.servicesFor(c).Orders.CancelOrder(username.(string), uint(orderID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "order_cancelled"), "refund": refund.Amount})
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// ListOrdersForFulfillment - заказы для выдачи, фильтр по статусу через ?status=
+func (h *Handler) ListOrdersForFulfillment(c *gin.Context) {
+	orders, err := h.services.Orders.ListOrdersByStatus(c.Query("status"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema": gin.H{
+			"orders": orders,
+		},
+	})
+}
+
+// UpdateOrderStatus - перевод заказа по этапам выдачи
+func (h *Handler) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+
+	order, err := h.services.Orders.UpdateOrderStatus(uint(orderID), req.Status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "order_status_updated"), "order": order})
+}
@@ -3,50 +3,114 @@ package handlers
 import (
 	"merch-store/services"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SendCoin - передача монет другому пользователю
-func SendCoin(c *gin.Context) {
+func (h *Handler) SendCoin(c *gin.Context) {
 	username, _ := c.Get("username")
 
-	var request struct {
-		ToUser string `json:"toUser"`
-		Amount int    `json:"amount"`
-	}
+	var request SendCoinRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"description": "Неверный запрос."})
+		respondInvalidRequest(c)
+		return
+	}
+	if errs := request.Validate(username.(string)); len(errs) > 0 {
+		respondValidationErrors(c, errs)
 		return
 	}
 
-	err := services.SendCoin(username.(string), request.ToUser, request.Amount)
+	transaction, err := h.servicesFor(c).Coins.SendCoin(username.(string), request.ToUser, request.Amount, request.Message)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"description": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"description": "Успешная передача монет."})
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "coins_sent"), "transactionId": transaction.ID, "createdAt": transaction.CreatedAt})
 }
 
 // BuyItem - покупка товара за монеты
-func BuyItem(c *gin.Context) {
+func (h *Handler) BuyItem(c *gin.Context) {
 	username, _ := c.Get("username")
 	itemName := c.Param("item")
 
-	var request struct {
-		Amount int `json:"amount"`
+	var request BuyItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondInvalidRequest(c)
+		return
+	}
+	if errs := request.Validate(itemName); len(errs) > 0 {
+		respondValidationErrors(c, errs)
+		return
+	}
+
+	order, err := h.servicesFor(c).Items.BuyItem(username.(string), itemName, request.Amount, request.Size, request.Color)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "item_purchased"), "amount": request.Amount, "orderId": order.ID})
+}
+
+// GetCoinHistory - постраничная история переводов с фильтрами
+// ?direction=sent|received, ?counterparty=, ?from= и ?to= (RFC3339 или YYYY-MM-DD), ?limit=, ?cursor=
+func (h *Handler) GetCoinHistory(c *gin.Context) {
+	username, _ := c.Get("username")
+
+	query := services.HistoryQuery{
+		Direction:    c.Query("direction"),
+		Counterparty: c.Query("counterparty"),
+		Cursor:       c.Query("cursor"),
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			respondError(c, services.ErrInvalidLimit)
+			return
+		}
+		query.Limit = limit
+	}
+
+	var err error
+	if query.From, err = parseHistoryDate(c.Query("from"), false); err != nil {
+		respondError(c, services.ErrInvalidDateRange)
+		return
 	}
-	if err := c.ShouldBindJSON(&request); err != nil || request.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"description": "Некорректное количество товара"})
+	if query.To, err = parseHistoryDate(c.Query("to"), true); err != nil {
+		respondError(c, services.ErrInvalidDateRange)
 		return
 	}
 
-	err := services.BuyItem(username.(string), itemName, request.Amount)
+	page, err := h.services.Coins.GetCoinHistory(username.(string), query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"description": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"description": "Товар приобретен", "amount": request.Amount})
+	c.JSON(http.StatusOK, gin.H{
+		"description": message(c, "ok"),
+		"schema":      page,
+	})
+}
+
+// parseHistoryDate - разбор границы периода; дата без времени в ?to= включает весь день
+func parseHistoryDate(value string, endOfRange bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
 }
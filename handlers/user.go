@@ -14,54 +14,66 @@ type RegisterRequest struct {
 }
 
 // Register - регистрация пользователя
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"description": "Неверный запрос."})
+		respondInvalidRequest(c)
 		return
 	}
 
-	err := services.RegisterUser(req.Username, req.Password)
+	err := h.services.Users.RegisterUser(req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"description": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"description": "Пользователь зарегистрирован."})
+	c.JSON(http.StatusOK, gin.H{"description": message(c, "user_registered")})
 }
 
 // Auth - аутентификация пользователя и выдача JWT-токена
-func Auth(c *gin.Context) {
+func (h *Handler) Auth(c *gin.Context) {
 	var creds models.User
 	if err := c.ShouldBindJSON(&creds); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"description": "Неверный запрос."})
+		respondInvalidRequest(c)
 		return
 	}
 
-	token, err := services.AuthenticateUser(creds.Username, creds.Password)
+	token, err := h.services.Users.AuthenticateUser(creds.Username, creds.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"description": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
-func GetUserInfo(c *gin.Context) {
+// GetUserInfo - информация о пользователе, ?coinHistory=grouped группирует переводы по участникам
+func (h *Handler) GetUserInfo(c *gin.Context) {
 	username, _ := c.Get("username")
 
-	userInfo, err := services.GetUserInfo(username.(string))
+	var grouped bool
+	switch c.Query("coinHistory") {
+	case "":
+	case "grouped":
+		grouped = true
+	default:
+		respondError(c, services.ErrInvalidHistoryView)
+		return
+	}
+
+	userInfo, err := h.services.Users.GetUserInfo(username.(string), grouped)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"description": "Внутренняя ошибка сервера."})
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"description": "Успешный ответ.",
+		"description": message(c, "ok"),
 		"schema": gin.H{
 			"coins":       userInfo.Coins,
 			"inventory":   userInfo.Inventory,
 			"coinHistory": userInfo.CoinHistory,
+			"orders":      userInfo.Orders,
 		},
 	})
 }
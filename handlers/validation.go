@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"merch-store/services"
+	"merch-store/utils"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldError - ошибка проверки отдельного поля запроса, Message заполняется на языке клиента при ответе
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors - набор ошибок проверки запроса
+type ValidationErrors []FieldError
+
+// add - добавление ошибки поля
+func (v *ValidationErrors) add(field, code string) {
+	*v = append(*v, FieldError{Field: field, Code: code})
+}
+
+type SendCoinRequest struct {
+	ToUser  string `json:"toUser"`
+	Amount  int    `json:"amount"`
+	Message string `json:"message"`
+}
+
+// Validate - проверка перевода: положительная сумма, непустой получатель, не сам отправитель, длина сообщения
+func (r SendCoinRequest) Validate(fromUser string) ValidationErrors {
+	var errs ValidationErrors
+	if strings.TrimSpace(r.ToUser) == "" {
+		errs.add("toUser", codeRecipientRequired)
+	} else if r.ToUser == fromUser {
+		errs.add("toUser", services.ErrSelfTransfer.Code)
+	}
+	if r.Amount <= 0 {
+		errs.add("amount", services.ErrInvalidAmount.Code)
+	}
+	if utf8.RuneCountInString(r.Message) > services.MaxTransferMessageLength {
+		errs.add("message", services.ErrMessageTooLong.Code)
+	}
+	return errs
+}
+
+type BuyItemRequest struct {
+	Amount int    `json:"amount"`
+	Size   string `json:"size"`
+	Color  string `json:"color"`
+}
+
+// Validate - проверка покупки: указан товар и количество от 1 до services.MaxOrderQuantity
+func (r BuyItemRequest) Validate(itemName string) ValidationErrors {
+	var errs ValidationErrors
+	if strings.TrimSpace(itemName) == "" {
+		errs.add("item", codeItemRequired)
+	}
+	if r.Amount <= 0 || r.Amount > services.MaxOrderQuantity {
+		errs.add("amount", services.ErrInvalidQuantity.Code)
+	}
+	return errs
+}
+
+type AddToCartRequest struct {
+	Item   string `json:"item"`
+	Amount int    `json:"amount"`
+	Size   string `json:"size"`
+	Color  string `json:"color"`
+}
+
+// Validate - проверка добавления в корзину: те же правила, что и для покупки
+func (r AddToCartRequest) Validate() ValidationErrors {
+	return BuyItemRequest{Amount: r.Amount}.Validate(r.Item)
+}
+
+// respondValidationErrors - ответ 400 с перечнем ошибок по полям
+func respondValidationErrors(c *gin.Context, errs ValidationErrors) {
+	lang := language(c)
+	for i := range errs {
+		errs[i].Message = utils.Message(lang, errs[i].Code, errs[i].Code)
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":        codeValidationError,
+		"description": utils.Message(lang, codeValidationError, codeValidationError),
+		"errors":      errs,
+	})
+}
@@ -3,36 +3,140 @@ package main
 import (
 	"github.com/gin-gonic/gin"
 	"log"
+	"merch-store/config"
 	"merch-store/handlers"
 	"merch-store/middlewares"
 	"merch-store/repositories"
+	"merch-store/services"
+	"merch-store/utils"
+	"os"
+	"time"
 )
 
 func main() {
+	// Подкоманде "migrate" (версии схемы) нужны только настройки базы данных:
+	// она не должна требовать, например, JWT_SECRET, без которого не запустится API
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		cfg, err := config.Read()
+		if err == nil {
+			err = cfg.Database.Validate()
+		}
+		if err != nil {
+			log.Fatal("Некорректные настройки:\n", err)
+		}
+		runMigrate(cfg.Database, os.Args[2:])
+		return
+	}
+
+	// Настройки из переменных окружения и необязательного YAML-файла (CONFIG_FILE)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Некорректные настройки:\n", err)
+	}
 
-	// Инициализация базы данных
-	repositories.InitDB()
+	// Подкоманда "reconcile" - сверка балансов, без аргументов - запуск API
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(cfg, os.Args[2:])
+		return
+	}
+
+	svc := setup(cfg)
 
-	// Проверка подключения
-	if err := repositories.DB.Ping(); err != nil {
-		log.Fatal("Ошибка подключения к базе данных:", err)
+	// Периодическая сверка балансов
+	if cfg.Reconcile.Interval > 0 {
+		svc.Reconciliation.ScheduleReconciliation(cfg.Reconcile.Interval, cfg.Reconcile.Adjust)
 	}
-	log.Println("Подключение к базе данных успешно!")
+
+	// Очистка устаревших ключей идемпотентности
+	svc.Idempotency.ScheduleCleanup(time.Hour)
+
+	h := handlers.New(svc)
+	idempotency := middlewares.IdempotencyMiddleware(svc.Idempotency)
 
 	r := gin.Default()
 
 	// Роуты для регистрации и авторизации
-	r.POST("/api/register", handlers.Register)
-	r.POST("/api/auth", handlers.Auth)
+	r.POST("/api/register", h.Register)
+	r.POST("/api/auth", h.Auth)
+
+	// Публичный каталог товаров
+	r.GET("/api/items", h.ListItems)
 
 	// Роуты для работы с монетами и товарами
 	auth := r.Group("/api")
 	auth.Use(middlewares.AuthMiddleware())
 	{
-		auth.GET("/info", handlers.GetUserInfo)
-		auth.POST("/sendCoin", handlers.SendCoin)
-		auth.POST("/buy/:item", handlers.BuyItem)
+		auth.GET("/info", h.GetUserInfo)
+		auth.GET("/history", h.GetCoinHistory)
+		auth.POST("/sendCoin", idempotency, h.SendCoin)
+		auth.POST("/buy/:item", idempotency, h.BuyItem)
+		auth.GET("/orders", h.GetOrders)
+		auth.POST("/orders/:id/cancel", idempotency, h.CancelOrder)
+		auth.GET("/cart", h.GetCart)
+		auth.POST("/cart", h.AddToCart)
+		auth.DELETE("/cart/:id", h.RemoveFromCart)
+		auth.POST("/cart/checkout", idempotency, h.Checkout)
+	}
+
+	// Роуты для управления каталогом, доступные только администраторам
+	admin := auth.Group("/admin")
+	admin.Use(middlewares.AdminMiddleware())
+	{
+		admin.POST("/items", h.CreateItem)
+		admin.PUT("/items/:item", h.UpdateItem)
+		admin.DELETE("/items/:item", h.DeactivateItem)
+		admin.POST("/items/:item/restock", h.RestockItem)
+		admin.POST("/items/:item/variants", h.CreateVariant)
+		admin.GET("/orders", h.ListOrdersForFulfillment)
+		admin.PUT("/orders/:id/status", h.UpdateOrderStatus)
+		admin.GET("/ledger/check", h.CheckLedger)
 	}
 
-	r.Run(":8080")
+	r.Run(cfg.ListenAddr)
+}
+
+// setup - подключение к хранилищу, наполнение каталога и создание сервисов с настройками из cfg
+func setup(cfg config.Config) *services.Services {
+	store, err := openStore(cfg.Database)
+	if err != nil {
+		log.Fatal("Не удалось подключиться к базе данных:", err)
+	}
+
+	if err := store.Items().SeedItems(cfg.Catalog); err != nil {
+		log.Fatal("Ошибка при наполнении каталога:", err)
+	}
+
+	utils.JwtSecret = []byte(cfg.JWT.Secret)
+	utils.JwtTTL = cfg.JWT.TTL
+
+	return services.New(store, services.Options{
+		StartingCoins: cfg.StartingCoins,
+		CancelWindow:  cfg.OrderCancelWindow,
+
+		IdempotencyTimeout: cfg.Idempotency.ReservationTimeout,
+		IdempotencyTTL:     cfg.Idempotency.TTL,
+	})
+}
+
+// openStore - хранилище, выбранное в настройках database.driver
+func openStore(cfg config.DatabaseConfig) (repositories.Store, error) {
+	switch cfg.Driver {
+	case config.DriverMemory:
+		log.Println("Данные хранятся в памяти и будут потеряны при перезапуске")
+		return repositories.NewMemoryStore(), nil
+	case config.DriverSqlite:
+		store, err := repositories.OpenSqlite(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Открыт файл базы данных", cfg.Path)
+		return store, nil
+	default:
+		store, err := repositories.OpenPostgres(cfg.ConnectionString())
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Подключение к базе данных успешно!")
+		return store, nil
+	}
 }
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"merch-store/models"
 	"merch-store/utils"
 	"net/http"
 	"strings"
@@ -14,16 +15,14 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"description": "Неавторизован."})
-			c.Abort()
+			abortWithCode(c, http.StatusUnauthorized, "unauthorized")
 			return
 		}
 
 		// Токен передается в формате "Bearer <token>"
 		tokenString := strings.Split(authHeader, " ")
 		if len(tokenString) != 2 {
-			c.JSON(http.StatusUnauthorized, gin.H{"description": "Неавторизован."})
-			c.Abort()
+			abortWithCode(c, http.StatusUnauthorized, "unauthorized")
 			return
 		}
 
@@ -31,28 +30,50 @@ func AuthMiddleware() gin.HandlerFunc {
 			return utils.JwtSecret, nil
 		})
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"description": "Неавторизован."})
-			c.Abort()
+			abortWithCode(c, http.StatusUnauthorized, "unauthorized")
 			return
 		}
 
 		// Получаем имя пользователя из токена
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"description": "Неавторизован."})
-			c.Abort()
+			abortWithCode(c, http.StatusUnauthorized, "unauthorized")
 			return
 		}
 
 		username, ok := claims["username"].(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"description": "Неавторизован."})
-			c.Abort()
+			abortWithCode(c, http.StatusUnauthorized, "unauthorized")
 			return
 		}
 
-		// Сохраняем username в контексте
+		// Токены, выданные до появления ролей, не содержат claim role
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = models.RoleUser
+		}
+
+		// Сохраняем username и роль в контексте
 		c.Set("username", username)
+		c.Set("role", role)
 		c.Next()
 	}
 }
+
+// AdminMiddleware - доступ только для администраторов, используется после AuthMiddleware
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != models.RoleAdmin {
+			abortWithCode(c, http.StatusForbidden, "forbidden")
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortWithCode - прерывание запроса с кодом ошибки и текстом на языке из Accept-Language
+func abortWithCode(c *gin.Context, status int, code string) {
+	lang := utils.Language(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", lang)
+	c.AbortWithStatusJSON(status, gin.H{"code": code, "description": utils.Message(lang, code, code)})
+}
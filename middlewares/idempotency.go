@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"merch-store/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseRecorder - копирует тело ответа, чтобы сохранить его для повторов
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware - обработка заголовка Idempotency-Key на изменяющих роутах, используется после AuthMiddleware.
+// Повтор с тем же ключом и телом возвращает сохраненный ответ, повтор с другим запросом - 422.
+// Резервация ключа передается обработчику в контексте, и операция фиксирует ключ в своей транзакции,
+// поэтому ключ зафиксированной операции не резервируется заново даже после сбоя до сохранения ответа.
+// Если обработчик паникует, ключ освобождается, и запрос можно повторить.
+func IdempotencyMiddleware(idempotency *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		username := c.GetString("username")
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithCode(c, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		request, saved, err := idempotency.BeginIdempotentRequest(username, key, requestHash)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			abortWithCode(c, http.StatusUnprocessableEntity, services.ErrIdempotencyKeyReused.Code)
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			abortWithCode(c, http.StatusConflict, services.ErrIdempotencyKeyInProgress.Code)
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyCommitted):
+			abortWithCode(c, http.StatusConflict, services.ErrIdempotencyKeyCommitted.Code)
+			return
+		case err != nil:
+			abortWithCode(c, http.StatusInternalServerError, "internal_error")
+			return
+		case saved != nil:
+			// Повтор запроса: возвращаем исходный ответ без повторного выполнения
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(*saved.StatusCode, "application/json; charset=utf-8", saved.Response)
+			c.Abort()
+			return
+		}
+
+		// Обработчик выполняет операцию через сервисы, которые фиксируют ключ в ее транзакции
+		c.Set("idempotentRequest", request)
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Паника обработчика перехватывается gin.Recovery снаружи; до этого ключ освобождается,
+		// иначе все повторы с ним получали бы 409
+		defer func() {
+			if r := recover(); r != nil {
+				if err := idempotency.ReleaseIdempotentRequest(request); err != nil {
+					log.Println("Ошибка освобождения ключа идемпотентности:", err)
+				}
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		err = idempotency.CompleteIdempotentRequest(request, recorder.Status(), recorder.body.Bytes())
+		if err != nil {
+			log.Println("Ошибка сохранения ответа для ключа идемпотентности:", err)
+		}
+	}
+}
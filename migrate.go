@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"merch-store/config"
+	"merch-store/repositories"
+	"os"
+	"text/tabwriter"
+)
+
+// runMigrate - подкоманда "migrate up|down|status": управление версиями схемы без запуска API.
+// down откатывает последнюю примененную миграцию, с флагом -steps - несколько.
+func runMigrate(cfg config.DatabaseConfig, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "сколько последних миграций откатить командой down")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: merch-store migrate up|down|status [-steps N]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	flags.Parse(args[1:])
+
+	migrator, err := openMigrator(cfg)
+	if err != nil {
+		log.Fatal("Не удалось подключиться к базе данных:", err)
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.MigrateUp()
+		if err != nil {
+			log.Fatal("Ошибка применения миграций:", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Схема уже в актуальной версии")
+		}
+	case "down":
+		if _, err := migrator.MigrateDown(*steps); err != nil {
+			log.Fatal("Ошибка отката миграций:", err)
+		}
+	case "status":
+		states, err := migrator.MigrationStatus()
+		if err != nil {
+			log.Fatal("Ошибка чтения версий схемы:", err)
+		}
+		printMigrationStatus(states)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// openMigrator - подключение к хранилищу из настроек без применения миграций
+func openMigrator(cfg config.DatabaseConfig) (repositories.Migrator, error) {
+	switch cfg.Driver {
+	case config.DriverMemory:
+		return nil, errors.New("хранилищу в памяти миграции не нужны")
+	case config.DriverSqlite:
+		return repositories.ConnectSqlite(cfg.Path)
+	default:
+		return repositories.ConnectPostgres(cfg.ConnectionString())
+	}
+}
+
+// printMigrationStatus - таблица версий схемы в stdout
+func printMigrationStatus(states []repositories.MigrationState) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ВЕРСИЯ\tМИГРАЦИЯ\tПРИМЕНЕНА")
+	for _, state := range states {
+		applied := "нет"
+		if state.AppliedAt != nil {
+			applied = state.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if state.Missing {
+			applied += " (файлы миграции отсутствуют)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, applied)
+	}
+	w.Flush()
+}
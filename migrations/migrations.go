@@ -0,0 +1,13 @@
+package migrations
+
+import "embed"
+
+// Миграции схемы: файлы NNNN_название.up.sql применяют версию, NNNN_название.down.sql откатывают ее.
+// Номера версий в каталогах postgres и sqlite совпадают.
+var (
+	//go:embed postgres/*.sql
+	Postgres embed.FS
+
+	//go:embed sqlite/*.sql
+	Sqlite embed.FS
+)
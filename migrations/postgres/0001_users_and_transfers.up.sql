@@ -0,0 +1,49 @@
+-- Создание таблицы пользователей
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    coins INT DEFAULT 1000 CONSTRAINT users_coins_non_negative CHECK (coins >= 0),
+    role TEXT NOT NULL DEFAULT 'user'
+);
+
+-- Базы, созданные до версионных миграций, могли не содержать новых столбцов и ограничений
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_coins_non_negative') THEN
+        ALTER TABLE users ADD CONSTRAINT users_coins_non_negative CHECK (coins >= 0);
+    END IF;
+END $$;
+
+-- Создание таблицы транзакций
+CREATE TABLE IF NOT EXISTS transactions (
+    id SERIAL PRIMARY KEY,
+    from_user TEXT REFERENCES users(name) ON DELETE CASCADE,
+    to_user TEXT REFERENCES users(name) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS message TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- Индексы для постраничной истории переводов
+CREATE INDEX IF NOT EXISTS idx_transactions_from_user ON transactions (from_user, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_to_user ON transactions (to_user, created_at DESC, id DESC);
+
+-- Создание таблицы инвентаря
+CREATE TABLE IF NOT EXISTS inventory (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    amount INT NOT NULL DEFAULT 1,
+    CONSTRAINT unique_user_item_variant UNIQUE (user_id, item_name, variant)
+);
+
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS variant TEXT NOT NULL DEFAULT '';
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS unique_user_item;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_user_item_variant ON inventory (user_id, item_name, variant);
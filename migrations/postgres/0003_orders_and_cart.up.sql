@@ -0,0 +1,41 @@
+-- История заказов
+CREATE TABLE IF NOT EXISTS orders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_id INT REFERENCES items(id),
+    variant_id INT REFERENCES item_variants(id),
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_price INT NOT NULL CHECK (unit_price > 0),
+    total INT NOT NULL CHECK (total > 0),
+    status TEXT NOT NULL DEFAULT 'placed',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS item_id INT REFERENCES items(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS variant_id INT REFERENCES item_variants(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'placed';
+
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status, created_at);
+
+-- Возвраты монет за отмененные заказы
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    order_id INT UNIQUE NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Корзина пользователя
+CREATE TABLE IF NOT EXISTS cart_items (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_name TEXT NOT NULL,
+    size TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    quantity INT NOT NULL CHECK (quantity > 0),
+    CONSTRAINT unique_cart_item UNIQUE (user_id, item_name, size, color)
+);
@@ -0,0 +1,10 @@
+-- Ключи идемпотентности и сохраненные ответы
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    username TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT,
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, key)
+);
@@ -0,0 +1,18 @@
+-- Входящие остатки: балансы пользователей, появившихся до журнала проводок, переносятся в журнал.
+-- Остаток записывается пользователям без стартового начисления (grant) и без входящего остатка
+-- и равен разнице между users.coins и уже записанными проводками по счету пользователя.
+-- В базе, где все пользователи зарегистрированы после появления журнала, миграция ничего не записывает.
+WITH opened AS (
+    INSERT INTO ledger_entries (account, delta, reason, reference)
+    SELECT 'user:' || u.name, u.coins - COALESCE(SUM(l.delta), 0), 'opening_balance', 'opening_balance'
+    FROM users u LEFT JOIN ledger_entries l ON l.account = 'user:' || u.name
+    WHERE NOT EXISTS (
+        SELECT 1 FROM ledger_entries o
+        WHERE o.account = 'user:' || u.name AND o.reason IN ('grant', 'opening_balance')
+    )
+    GROUP BY u.name, u.coins
+    HAVING u.coins <> COALESCE(SUM(l.delta), 0)
+    RETURNING delta
+)
+INSERT INTO ledger_entries (account, delta, reason, reference)
+SELECT 'system:opening', -SUM(delta), 'opening_balance', 'opening_balance' FROM opened HAVING SUM(delta) <> 0;
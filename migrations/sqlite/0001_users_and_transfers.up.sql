@@ -0,0 +1,32 @@
+-- Создание таблицы пользователей
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    coins INT DEFAULT 1000 CONSTRAINT users_coins_non_negative CHECK (coins >= 0),
+    role TEXT NOT NULL DEFAULT 'user'
+);
+
+-- Создание таблицы транзакций; время хранится строкой, которая сравнивается в хронологическом порядке
+CREATE TABLE IF NOT EXISTS transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_user TEXT REFERENCES users(name) ON DELETE CASCADE,
+    to_user TEXT REFERENCES users(name) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+-- Индексы для постраничной истории переводов
+CREATE INDEX IF NOT EXISTS idx_transactions_from_user ON transactions (from_user, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_to_user ON transactions (to_user, created_at DESC, id DESC);
+
+-- Создание таблицы инвентаря
+CREATE TABLE IF NOT EXISTS inventory (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    amount INT NOT NULL DEFAULT 1,
+    CONSTRAINT unique_user_item_variant UNIQUE (user_id, item_name, variant)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS restocks;
+DROP TABLE IF EXISTS item_variants;
+DROP TABLE IF EXISTS items;
@@ -0,0 +1,29 @@
+-- Создание каталога мерча
+CREATE TABLE IF NOT EXISTS items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    price INT NOT NULL CHECK (price > 0),
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    stock INT CHECK (stock >= 0)
+);
+
+-- Варианты товаров (размер, цвет) со своими остатками
+CREATE TABLE IF NOT EXISTS item_variants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INT NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    size TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    stock INT CHECK (stock >= 0),
+    CONSTRAINT unique_item_variant UNIQUE (item_id, size, color)
+);
+
+-- Журнал пополнения склада
+CREATE TABLE IF NOT EXISTS restocks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INT REFERENCES items(id) ON DELETE CASCADE,
+    variant_id INT REFERENCES item_variants(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    admin TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
@@ -0,0 +1,37 @@
+-- История заказов
+CREATE TABLE IF NOT EXISTS orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_id INT REFERENCES items(id),
+    variant_id INT REFERENCES item_variants(id),
+    item_name TEXT NOT NULL,
+    variant TEXT NOT NULL DEFAULT '',
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_price INT NOT NULL CHECK (unit_price > 0),
+    total INT NOT NULL CHECK (total > 0),
+    status TEXT NOT NULL DEFAULT 'placed',
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status, created_at);
+
+-- Возвраты монет за отмененные заказы
+CREATE TABLE IF NOT EXISTS refunds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INT UNIQUE NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+-- Корзина пользователя
+CREATE TABLE IF NOT EXISTS cart_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_name TEXT NOT NULL,
+    size TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    quantity INT NOT NULL CHECK (quantity > 0),
+    CONSTRAINT unique_cart_item UNIQUE (user_id, item_name, size, color)
+);
@@ -0,0 +1,12 @@
+-- Журнал проводок: каждая операция с монетами записывается сбалансированными изменениями счетов
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account TEXT NOT NULL,
+    delta INT NOT NULL CHECK (delta <> 0),
+    reason TEXT NOT NULL,
+    reference TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries (account);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries (reference);
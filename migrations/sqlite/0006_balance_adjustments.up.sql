@@ -0,0 +1,8 @@
+-- Корректировки, которыми сверка балансов объясняет расхождение users.coins с историей операций
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL CHECK (amount <> 0),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
@@ -0,0 +1 @@
+DELETE FROM ledger_entries WHERE reason = 'opening_balance';
@@ -0,0 +1,21 @@
+-- Входящие остатки: балансы пользователей, появившихся до журнала проводок, переносятся в журнал.
+-- Условия совпадают с migrations/postgres; SQLite не поддерживает INSERT в WITH,
+-- поэтому сначала записывается встречная проводка system:opening, затем остатки пользователей.
+CREATE TEMP VIEW opening_balances AS
+SELECT 'user:' || u.name AS account, u.coins - COALESCE(SUM(l.delta), 0) AS delta
+FROM users u LEFT JOIN ledger_entries l ON l.account = 'user:' || u.name
+WHERE NOT EXISTS (
+    SELECT 1 FROM ledger_entries o
+    WHERE o.account = 'user:' || u.name AND o.reason IN ('grant', 'opening_balance')
+)
+GROUP BY u.name, u.coins
+HAVING u.coins <> COALESCE(SUM(l.delta), 0);
+
+INSERT INTO ledger_entries (account, delta, reason, reference)
+SELECT 'system:opening', -total, 'opening_balance', 'opening_balance'
+FROM (SELECT SUM(delta) AS total FROM opening_balances) WHERE total <> 0;
+
+INSERT INTO ledger_entries (account, delta, reason, reference)
+SELECT account, delta, 'opening_balance', 'opening_balance' FROM opening_balances;
+
+DROP VIEW opening_balances;
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys DROP COLUMN committed_at;
+ALTER TABLE idempotency_keys DROP COLUMN token;
@@ -0,0 +1,4 @@
+-- Токен резервирования ключа идемпотентности и время, когда операция по ключу зафиксирована.
+-- committed_at записывается в одной транзакции с операцией, такой ключ больше не резервируется заново.
+ALTER TABLE idempotency_keys ADD COLUMN token TEXT NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ADD COLUMN committed_at TIMESTAMP;
@@ -0,0 +1,14 @@
+package models
+
+// CartItem - позиция корзины пользователя
+type CartItem struct {
+	ID       uint   `db:"id" json:"id"`
+	UserID   uint   `db:"user_id" json:"-"`
+	ItemName string `db:"item_name" json:"item"`
+	Size     string `db:"size" json:"size,omitempty"`
+	Color    string `db:"color" json:"color,omitempty"`
+	Quantity int    `db:"quantity" json:"quantity"`
+	// UnitPrice и Total рассчитываются по текущему каталогу при выдаче корзины
+	UnitPrice int `db:"-" json:"unitPrice"`
+	Total     int `db:"-" json:"total"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyKey - сохраненный результат запроса с заголовком Idempotency-Key
+type IdempotencyKey struct {
+	Username    string `db:"username"`
+	Key         string `db:"key"`
+	RequestHash string `db:"request_hash"`
+	StatusCode  *int   `db:"status_code"`
+	Response    []byte `db:"response"`
+	// CreatedAt - время резервирования ключа; обновляется, когда зависший ключ резервируется заново
+	CreatedAt time.Time `db:"created_at"`
+	// CommittedAt - время фиксации операции по ключу, nil, пока операция не зафиксирована
+	CommittedAt *time.Time `db:"committed_at"`
+}
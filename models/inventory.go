@@ -4,5 +4,6 @@ type Inventory struct {
 	ID       uint   `db:"id"`
 	UserID   uint   `db:"user_id"`
 	ItemName string `db:"item_name"`
+	Variant  string `db:"variant"`
 	Amount   int    `db:"amount"`
 }
@@ -1,6 +1,35 @@
 package models
 
+import "strings"
+
 type Item struct {
-	Name  string
-	Price int
+	ID          uint   `db:"id" json:"-"`
+	Name        string `db:"name" json:"name"`
+	Price       int    `db:"price" json:"price"`
+	Description string `db:"description" json:"description"`
+	Active      bool   `db:"active" json:"active"`
+	// Stock - остаток на складе, nil означает отсутствие ограничения
+	Stock    *int          `db:"stock" json:"stock"`
+	Variants []ItemVariant `db:"-" json:"variants,omitempty"`
+}
+
+// ItemVariant - вариант товара (размер и/или цвет) со своим остатком
+type ItemVariant struct {
+	ID     uint   `db:"id" json:"-"`
+	ItemID uint   `db:"item_id" json:"-"`
+	Size   string `db:"size" json:"size,omitempty"`
+	Color  string `db:"color" json:"color,omitempty"`
+	Stock  *int   `db:"stock" json:"stock"`
+}
+
+// Label - подпись варианта вида "L, black"
+func (v ItemVariant) Label() string {
+	parts := make([]string, 0, 2)
+	if v.Size != "" {
+		parts = append(parts, v.Size)
+	}
+	if v.Color != "" {
+		parts = append(parts, v.Color)
+	}
+	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Системные счета двойной записи; счет пользователя - UserAccount(имя)
+const (
+	AccountGrants  = "system:grants"
+	AccountShop    = "system:shop"
+	AccountOpening = "system:opening"
+)
+
+// Основания проводок
+const (
+	LedgerGrant          = "grant"
+	LedgerTransfer       = "transfer"
+	LedgerPurchase       = "purchase"
+	LedgerRefund         = "refund"
+	LedgerOpeningBalance = "opening_balance"
+)
+
+// LedgerEntry - проводка по счету; проводки одного reference в сумме дают ноль
+type LedgerEntry struct {
+	ID        uint      `db:"id" json:"id"`
+	Account   string    `db:"account" json:"account"`
+	Delta     int       `db:"delta" json:"delta"`
+	Reason    string    `db:"reason" json:"reason"`
+	Reference string    `db:"reference" json:"reference"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Posting - изменение одного счета в составе проводки
+type Posting struct {
+	Account string
+	Delta   int
+}
+
+// UserAccount - счет пользователя в журнале проводок
+func UserAccount(username string) string {
+	return "user:" + username
+}
+
+// OrderReference - ссылка проводки на заказ: оплата и возврат относятся к одному заказу
+func OrderReference(orderID uint) string {
+	return "order:" + strconv.FormatUint(uint64(orderID), 10)
+}
+
+// TransactionReference - ссылка проводки на перевод монет
+func TransactionReference(transactionID uint) string {
+	return "transaction:" + strconv.FormatUint(uint64(transactionID), 10)
+}
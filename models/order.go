@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Статусы заказа: placed -> packed -> ready_for_pickup -> delivered, отмена возможна до выдачи
+const (
+	OrderPlaced         = "placed"
+	OrderPacked         = "packed"
+	OrderReadyForPickup = "ready_for_pickup"
+	OrderDelivered      = "delivered"
+	OrderCancelled      = "cancelled"
+)
+
+// Order - заказ. ItemID равен nil у заказов, созданных до появления orders.item_id,
+// товар таких заказов определяется по ItemName.
+type Order struct {
+	ID        uint      `db:"id" json:"id"`
+	UserID    uint      `db:"user_id" json:"-"`
+	ItemID    *uint     `db:"item_id" json:"-"`
+	VariantID *uint     `db:"variant_id" json:"-"`
+	ItemName  string    `db:"item_name" json:"item"`
+	Variant   string    `db:"variant" json:"variant,omitempty"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	UnitPrice int       `db:"unit_price" json:"unitPrice"`
+	Total     int       `db:"total" json:"total"`
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Refund - возврат монет за отмененный заказ
+type Refund struct {
+	ID        uint      `db:"id" json:"id"`
+	OrderID   uint      `db:"order_id" json:"orderId"`
+	UserID    uint      `db:"user_id" json:"-"`
+	Amount    int       `db:"amount" json:"amount"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
@@ -1,8 +1,12 @@
 package models
 
+import "time"
+
 type Transaction struct {
-	ID       uint   `db:"id"`
-	FromUser string `db:"from_user"`
-	ToUser   string `db:"to_user"`
-	Amount   int    `db:"amount"`
+	ID        uint      `db:"id" json:"id"`
+	FromUser  string    `db:"from_user" json:"fromUser"`
+	ToUser    string    `db:"to_user" json:"toUser"`
+	Amount    int       `db:"amount" json:"amount"`
+	Message   string    `db:"message" json:"message,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
 }
@@ -1,8 +1,15 @@
 package models
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID       uint   `db:"id"`
 	Username string `db:"name"`
 	Password string `db:"password"`
 	Coins    int    `db:"coins"`
+	Role     string `db:"role"`
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"merch-store/config"
+	"os"
+)
+
+// runReconcile - подкоманда "reconcile": сверка балансов с историей операций, отчет в JSON в stdout.
+// С флагом -adjust расхождения закрываются корректировками. Код выхода 1, если остались расхождения.
+func runReconcile(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	adjust := flags.Bool("adjust", false, "привести балансы с расхождениями к журналу проводок")
+	flags.Parse(args)
+
+	svc := setup(cfg)
+
+	report, err := svc.Reconciliation.Reconcile(*adjust)
+	if err != nil {
+		log.Fatal("Ошибка сверки балансов:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("Ошибка вывода отчета:", err)
+	}
+
+	if len(report.Mismatches) > 0 && !report.Adjusted {
+		os.Exit(1)
+	}
+}
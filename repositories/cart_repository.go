@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"merch-store/models"
+
+	"github.com/lib/pq"
+)
+
+// cartColumns - поля позиции корзины, которые читаются из таблицы cart_items
+const cartColumns = "id, user_id, item_name, size, color, quantity"
+
+// ListCart возвращает позиции корзины пользователя в порядке добавления
+func (s *PostgresStore) ListCart(userID uint) ([]models.CartItem, error) {
+	items := []models.CartItem{}
+	err := s.q.Select(&items, "SELECT "+cartColumns+" FROM cart_items WHERE user_id=$1 ORDER BY id", userID)
+	return items, err
+}
+
+// AddToCart добавляет товар в корзину, увеличивая количество, если такая позиция уже есть
+func (s *PostgresStore) AddToCart(item *models.CartItem) error {
+	return s.q.Get(item,
+		`INSERT INTO cart_items (user_id, item_name, size, color, quantity) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, item_name, size, color) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+		RETURNING `+cartColumns,
+		item.UserID, item.ItemName, item.Size, item.Color, item.Quantity)
+}
+
+// RemoveFromCart удаляет позицию корзины, возвращает false, если позиции нет
+func (s *PostgresStore) RemoveFromCart(userID, cartItemID uint) (bool, error) {
+	result, err := s.q.Exec("DELETE FROM cart_items WHERE id=$1 AND user_id=$2", cartItemID, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ListCartForUpdate возвращает позиции корзины и блокирует их до конца транзакции оформления заказа
+func (s *PostgresStore) ListCartForUpdate(userID uint) ([]models.CartItem, error) {
+	items := []models.CartItem{}
+	err := s.q.Select(&items, "SELECT "+cartColumns+" FROM cart_items WHERE user_id=$1 ORDER BY id FOR UPDATE", userID)
+	return items, err
+}
+
+// RemoveCartItems удаляет перечисленные позиции корзины пользователя и возвращает число удаленных
+func (s *PostgresStore) RemoveCartItems(userID uint, cartItemIDs []uint) (int64, error) {
+	ids := make([]int64, len(cartItemIDs))
+	for i, id := range cartItemIDs {
+		ids[i] = int64(id)
+	}
+
+	result, err := s.q.Exec("DELETE FROM cart_items WHERE user_id=$1 AND id = ANY($2)", userID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
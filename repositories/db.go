@@ -1,79 +1,178 @@
 package repositories
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
-	"os"
+	"merch-store/config"
+	"merch-store/migrations"
+	"merch-store/models"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-var DB *sqlx.DB
+// dbtx - общие методы соединения и транзакции sqlx
+type dbtx interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
 
-// InitDB инициализирует соединение с базой данных
-func InitDB() {
-	var err error
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	connectionString := fmt.Sprintf("host=%s port=5431 user=postgres password=password dbname=shop sslmode=disable", host)
+// PostgresStore - реализация Store поверх PostgreSQL.
+// Вне транзакции запросы выполняются через пул соединений, внутри Atomic - через открытую транзакцию.
+type PostgresStore struct {
+	db *sqlx.DB
+	q  dbtx
+	tx *sqlx.Tx
+}
+
+var (
+	_ Store    = (*PostgresStore)(nil)
+	_ Migrator = (*PostgresStore)(nil)
+)
 
-	DB, err = sqlx.Connect("postgres", connectionString)
+// NewPostgresStore создает хранилище поверх готового соединения, миграции не выполняются
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{db: db, q: db}
+}
+
+// ConnectPostgres подключается к базе данных по строке подключения без применения миграций
+func ConnectPostgres(connectionString string) (*PostgresStore, error) {
+	db, err := sqlx.Connect("postgres", connectionString)
 	if err != nil {
-		log.Fatal("Не удалось подключиться к базе данных:", err)
+		return nil, fmt.Errorf("connect: %w", err)
 	}
 
 	log.Println("Успешное подключение к базе данных!")
+	return NewPostgresStore(db), nil
+}
 
-	// Выполняем дополнительные миграции, если необходимо
-	runMigrations()
+// OpenPostgres подключается к базе данных и применяет недостающие миграции
+func OpenPostgres(connectionString string) (*PostgresStore, error) {
+	store, err := ConnectPostgres(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := store.MigrateUp(); err != nil {
+		store.db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return store, nil
 }
 
-func InitTestDB() {
-	var err error
-	DB, err = sqlx.Connect("postgres", "host=localhost port=5430 user=postgres password=password dbname=test sslmode=disable")
+// InitTestDB подключается к тестовой базе данных и наполняет каталог
+func InitTestDB() (*PostgresStore, error) {
+	store, err := OpenPostgres("host=localhost port=5430 user=postgres password=password dbname=test sslmode=disable")
 	if err != nil {
-		log.Fatal("Не удалось подключиться к базе данных:", err)
+		return nil, err
 	}
 
-	log.Println("Успешное подключение к базе данных!")
+	if err := store.SeedItems(config.Default().Catalog); err != nil {
+		return nil, fmt.Errorf("seed items: %w", err)
+	}
 
-	// Выполняем дополнительные миграции, если необходимо
-	runMigrations()
+	return store, nil
 }
 
-// runMigrations проверяет и создает структуру БД, если она не существует
-func runMigrations() {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		name TEXT UNIQUE NOT NULL,
-		password TEXT NOT NULL,
-		coins INT DEFAULT 1000
-	);
-
-	CREATE TABLE IF NOT EXISTS transactions (
-		id SERIAL PRIMARY KEY,
-		from_user TEXT REFERENCES users(name) ON DELETE CASCADE,
-		to_user TEXT REFERENCES users(name) ON DELETE CASCADE,
-		amount INT NOT NULL CHECK (amount > 0)
-	);
-
-	CREATE TABLE IF NOT EXISTS inventory (
-		id SERIAL PRIMARY KEY,
-		user_id INT REFERENCES users(id) ON DELETE CASCADE,
-		item_name TEXT NOT NULL,
-		amount INT NOT NULL DEFAULT 1,
-		CONSTRAINT unique_user_item UNIQUE (user_id, item_name)
-	);
-	`
-
-	_, err := DB.Exec(schema)
+// DB возвращает соединение с базой данных
+func (s *PostgresStore) DB() *sqlx.DB { return s.db }
+
+func (s *PostgresStore) Users() UserRepository                  { return s }
+func (s *PostgresStore) Transactions() TransactionRepository    { return s }
+func (s *PostgresStore) Inventory() InventoryRepository         { return s }
+func (s *PostgresStore) Items() ItemRepository                  { return s }
+func (s *PostgresStore) Orders() OrderRepository                { return s }
+func (s *PostgresStore) Carts() CartRepository                  { return s }
+func (s *PostgresStore) IdempotencyKeys() IdempotencyRepository { return s }
+func (s *PostgresStore) Ledger() LedgerRepository               { return s }
+
+// Atomic выполняет fn в транзакции базы данных
+func (s *PostgresStore) Atomic(fn func(tx Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.Beginx()
 	if err != nil {
-		log.Fatal("Ошибка при выполнении миграций:", err)
+		return fmt.Errorf("begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	log.Println("Миграции выполнены успешно!")
+	if err := fn(&PostgresStore{db: s.db, q: tx, tx: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// migrationLockKey - ключ advisory-блокировки, под которой экземпляры сервиса по очереди применяют миграции
+const migrationLockKey = 20250214
+
+// migrator - миграции из migrations/postgres под advisory-блокировкой
+func (s *PostgresStore) migrator() *migrator {
+	files, _ := fs.Sub(migrations.Postgres, "postgres")
+	return &migrator{
+		db:    s.db,
+		files: files,
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		lock: func(conn *sqlx.Conn) (func(), error) {
+			ctx := context.Background()
+			if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+				return nil, err
+			}
+			return func() { conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey) }, nil
+		},
+	}
+}
+
+// MigrateUp применяет недостающие миграции
+func (s *PostgresStore) MigrateUp() ([]Migration, error) { return s.migrator().up() }
+
+// MigrateDown откатывает steps последних примененных миграций
+func (s *PostgresStore) MigrateDown(steps int) ([]Migration, error) { return s.migrator().down(steps) }
+
+// MigrationStatus возвращает известные и примененные миграции
+func (s *PostgresStore) MigrationStatus() ([]MigrationState, error) { return s.migrator().status() }
+
+// SeedItems добавляет в каталог товары, которых в нем еще нет; существующие товары не изменяются
+func (s *PostgresStore) SeedItems(items []models.Item) error {
+	for _, item := range items {
+		_, err := s.q.Exec(
+			"INSERT INTO items (name, price, description, stock) VALUES ($1, $2, $3, $4) ON CONFLICT (name) DO NOTHING",
+			item.Name, item.Price, item.Description, item.Stock)
+		if err != nil {
+			return fmt.Errorf("seed item %s: %w", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// IsUniqueViolation проверяет, что ошибка вызвана нарушением ограничения уникальности
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// storeError переводит ошибки PostgreSQL в ошибки хранилища ErrNotFound и ErrDuplicate
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case IsUniqueViolation(err):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
 }
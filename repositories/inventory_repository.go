@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"merch-store/models"
+)
+
+// ListInventory возвращает инвентарь пользователя
+func (s *PostgresStore) ListInventory(userID uint) ([]models.Inventory, error) {
+	rows := []models.Inventory{}
+	err := s.q.Select(&rows, "SELECT item_name, variant, amount FROM inventory WHERE user_id=$1", userID)
+	return rows, err
+}
+
+// AddInventory добавляет товар в инвентарь в рамках транзакции покупки
+func (s *PostgresStore) AddInventory(userID uint, itemName, variant string, amount int) error {
+	_, err := s.q.Exec(
+		"INSERT INTO inventory (user_id, item_name, variant, amount) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, item_name, variant) DO UPDATE SET amount = inventory.amount + EXCLUDED.amount",
+		userID, itemName, variant, amount)
+	return err
+}
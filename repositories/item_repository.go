@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+
+	"github.com/lib/pq"
+)
+
+// itemColumns - поля товара, которые читаются из таблицы items
+const itemColumns = "id, name, price, description, active, stock"
+
+// itemOrderBy - сортировка каталога по ключу ItemRepository.ListActiveItems
+var itemOrderBy = map[string]string{
+	"":       "name ASC",
+	"name":   "name ASC",
+	"-name":  "name DESC",
+	"price":  "price ASC, name ASC",
+	"-price": "price DESC, name ASC",
+}
+
+// GetItem возвращает товар из каталога, включая снятые с продажи
+func (s *PostgresStore) GetItem(name string) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item, "SELECT "+itemColumns+" FROM items WHERE name=$1", name)
+	return item, storeError(err)
+}
+
+// GetActiveItem возвращает товар из каталога, доступный для покупки
+func (s *PostgresStore) GetActiveItem(name string) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item, "SELECT "+itemColumns+" FROM items WHERE name=$1 AND active", name)
+	return item, storeError(err)
+}
+
+// ListActiveItems возвращает доступные для покупки товары.
+// maxPrice <= 0 означает отсутствие ограничения по цене.
+func (s *PostgresStore) ListActiveItems(maxPrice int, sort string) ([]models.Item, error) {
+	orderBy, ok := itemOrderBy[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown item sort %q", sort)
+	}
+
+	query := "SELECT " + itemColumns + " FROM items WHERE active"
+	args := []interface{}{}
+	if maxPrice > 0 {
+		query += " AND price <= $1"
+		args = append(args, maxPrice)
+	}
+	query += " ORDER BY " + orderBy
+
+	items := []models.Item{}
+	err := s.q.Select(&items, query, args...)
+	return items, err
+}
+
+// CreateItem добавляет товар в каталог
+func (s *PostgresStore) CreateItem(item *models.Item) error {
+	err := s.q.Get(item,
+		"INSERT INTO items (name, price, description, active, stock) VALUES ($1, $2, $3, $4, $5) RETURNING "+itemColumns,
+		item.Name, item.Price, item.Description, item.Active, item.Stock)
+	return storeError(err)
+}
+
+// UpdateItem изменяет переданные (не nil) поля товара, включая неактивные товары
+func (s *PostgresStore) UpdateItem(name string, price *int, description *string, active *bool) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item,
+		`UPDATE items SET price = COALESCE($2, price), description = COALESCE($3, description), active = COALESCE($4, active)
+		WHERE name=$1 RETURNING `+itemColumns,
+		name, price, description, active)
+	return item, storeError(err)
+}
+
+// DeactivateItem снимает товар с продажи, возвращает false, если товара нет в каталоге
+func (s *PostgresStore) DeactivateItem(name string) (bool, error) {
+	result, err := s.q.Exec("UPDATE items SET active = FALSE WHERE name=$1", name)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DecrementStock списывает товар со склада в рамках транзакции покупки.
+// Возвращает false, если остатка недостаточно. Товары без учета остатков (stock IS NULL) не ограничены.
+func (s *PostgresStore) DecrementStock(itemID uint, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE items SET stock = stock - $1 WHERE id = $2 AND (stock IS NULL OR stock >= $1)", amount, itemID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// RestockItem пополняет остаток товара и записывает операцию в журнал пополнений.
+// Для товара без учета остатков первое пополнение включает учет.
+func (s *PostgresStore) RestockItem(name string, quantity int, admin string) (models.Item, error) {
+	var item models.Item
+	err := s.Atomic(func(tx Store) error {
+		q := tx.(*PostgresStore).q
+		err := q.Get(&item, "UPDATE items SET stock = COALESCE(stock, 0) + $2 WHERE name=$1 RETURNING "+itemColumns, name, quantity)
+		if err != nil {
+			return storeError(err)
+		}
+
+		_, err = q.Exec("INSERT INTO restocks (item_id, quantity, admin) VALUES ($1, $2, $3)", item.ID, quantity, admin)
+		return err
+	})
+	return item, err
+}
+
+// variantColumns - поля варианта товара, которые читаются из таблицы item_variants
+const variantColumns = "id, item_id, size, color, stock"
+
+// GetItemVariants возвращает варианты товара
+func (s *PostgresStore) GetItemVariants(itemID uint) ([]models.ItemVariant, error) {
+	variants := []models.ItemVariant{}
+	err := s.q.Select(&variants, "SELECT "+variantColumns+" FROM item_variants WHERE item_id=$1 ORDER BY id", itemID)
+	return variants, err
+}
+
+// ListVariantsForItems возвращает варианты сразу для нескольких товаров
+func (s *PostgresStore) ListVariantsForItems(itemIDs []uint) ([]models.ItemVariant, error) {
+	ids := make([]int64, len(itemIDs))
+	for i, id := range itemIDs {
+		ids[i] = int64(id)
+	}
+
+	variants := []models.ItemVariant{}
+	err := s.q.Select(&variants, "SELECT "+variantColumns+" FROM item_variants WHERE item_id = ANY($1) ORDER BY id", pq.Array(ids))
+	return variants, err
+}
+
+// CreateVariant добавляет вариант товара
+func (s *PostgresStore) CreateVariant(variant *models.ItemVariant) error {
+	err := s.q.Get(variant,
+		"INSERT INTO item_variants (item_id, size, color, stock) VALUES ($1, $2, $3, $4) RETURNING "+variantColumns,
+		variant.ItemID, variant.Size, variant.Color, variant.Stock)
+	return storeError(err)
+}
+
+// DecrementVariantStock списывает вариант товара со склада в рамках транзакции покупки.
+// Возвращает false, если остатка недостаточно.
+func (s *PostgresStore) DecrementVariantStock(variantID uint, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE item_variants SET stock = stock - $1 WHERE id = $2 AND (stock IS NULL OR stock >= $1)", amount, variantID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// RestockVariant пополняет остаток варианта товара и записывает операцию в журнал пополнений
+func (s *PostgresStore) RestockVariant(name, size, color string, quantity int, admin string) (models.ItemVariant, error) {
+	var variant models.ItemVariant
+	err := s.Atomic(func(tx Store) error {
+		q := tx.(*PostgresStore).q
+		err := q.Get(&variant,
+			`UPDATE item_variants SET stock = COALESCE(stock, 0) + $4
+			WHERE item_id = (SELECT id FROM items WHERE name=$1) AND size=$2 AND color=$3 RETURNING `+variantColumns,
+			name, size, color, quantity)
+		if err != nil {
+			return storeError(err)
+		}
+
+		_, err = q.Exec("INSERT INTO restocks (item_id, variant_id, quantity, admin) VALUES ($1, $2, $3, $4)",
+			variant.ItemID, variant.ID, quantity, admin)
+		return err
+	})
+	return variant, err
+}
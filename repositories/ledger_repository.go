@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+
+	"github.com/lib/pq"
+)
+
+// PostLedgerEntries записывает сбалансированную проводку в рамках транзакции:
+// сумма изменений по всем счетам должна быть равна нулю
+func (s *PostgresStore) PostLedgerEntries(reason, reference string, postings ...models.Posting) error {
+	accounts := make([]string, len(postings))
+	deltas := make([]int64, len(postings))
+	var sum int64
+	for i, p := range postings {
+		accounts[i] = p.Account
+		deltas[i] = int64(p.Delta)
+		sum += deltas[i]
+	}
+	if sum != 0 {
+		return fmt.Errorf("unbalanced ledger posting %s %s: sum %d", reason, reference, sum)
+	}
+
+	_, err := s.q.Exec(
+		"INSERT INTO ledger_entries (account, delta, reason, reference) SELECT unnest($1::text[]), unnest($2::int[]), $3, $4",
+		pq.Array(accounts), pq.Array(deltas), reason, reference)
+	return err
+}
+
+// BalanceMismatch - расхождение между users.coins и суммой проводок по счету пользователя
+type BalanceMismatch struct {
+	UserID   uint   `db:"id" json:"-"`
+	Username string `db:"name" json:"username"`
+	Coins    int    `db:"coins" json:"coins"`
+	Ledger   int    `db:"ledger" json:"ledger"`
+	// Difference - на сколько users.coins больше баланса по журналу
+	Difference int `db:"difference" json:"difference"`
+}
+
+// ListBalanceMismatches возвращает пользователей, у которых баланс не совпадает с журналом проводок
+func (s *PostgresStore) ListBalanceMismatches() ([]BalanceMismatch, error) {
+	mismatches := []BalanceMismatch{}
+	err := s.q.Select(&mismatches, `
+		SELECT u.id, u.name, u.coins, COALESCE(SUM(l.delta), 0) AS ledger, u.coins - COALESCE(SUM(l.delta), 0) AS difference
+		FROM users u LEFT JOIN ledger_entries l ON l.account = 'user:' || u.name
+		GROUP BY u.id, u.name, u.coins
+		HAVING u.coins <> COALESCE(SUM(l.delta), 0)
+		ORDER BY u.name`)
+	return mismatches, err
+}
+
+// UnbalancedReference - проводка, изменения которой в сумме не равны нулю
+type UnbalancedReference struct {
+	Reference string `db:"reference" json:"reference"`
+	Sum       int    `db:"sum" json:"sum"`
+}
+
+// ListUnbalancedReferences возвращает проводки с ненулевой суммой изменений
+func (s *PostgresStore) ListUnbalancedReferences() ([]UnbalancedReference, error) {
+	unbalanced := []UnbalancedReference{}
+	err := s.q.Select(&unbalanced,
+		"SELECT reference, SUM(delta) AS sum FROM ledger_entries GROUP BY reference HAVING SUM(delta) <> 0 ORDER BY reference")
+	return unbalanced, err
+}
@@ -0,0 +1,491 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+	"sort"
+	"time"
+)
+
+// itemIndex - позиция товара по названию, -1 если его нет
+func (d *memoryData) itemIndex(name string) int {
+	for i := range d.items {
+		if d.items[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SeedItems добавляет в каталог товары, которых в нем еще нет; существующие товары не изменяются
+func (s *MemoryStore) SeedItems(items []models.Item) error {
+	defer s.lock()()
+
+	for _, item := range items {
+		if s.data.itemIndex(item.Name) >= 0 {
+			continue
+		}
+		s.data.seq.items++
+		s.data.items = append(s.data.items, models.Item{
+			ID: s.data.seq.items, Name: item.Name, Price: item.Price, Description: item.Description, Active: true, Stock: item.Stock,
+		})
+	}
+	return nil
+}
+
+// GetItem возвращает товар из каталога, включая снятые с продажи
+func (s *MemoryStore) GetItem(name string) (models.Item, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 {
+		return models.Item{}, ErrNotFound
+	}
+	return s.data.items[i], nil
+}
+
+// GetActiveItem возвращает товар из каталога, доступный для покупки
+func (s *MemoryStore) GetActiveItem(name string) (models.Item, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 || !s.data.items[i].Active {
+		return models.Item{}, ErrNotFound
+	}
+	return s.data.items[i], nil
+}
+
+// itemLess - сравнение товаров по ключу сортировки ListActiveItems
+var itemLess = map[string]func(a, b models.Item) bool{
+	"":      func(a, b models.Item) bool { return a.Name < b.Name },
+	"name":  func(a, b models.Item) bool { return a.Name < b.Name },
+	"-name": func(a, b models.Item) bool { return a.Name > b.Name },
+	"price": func(a, b models.Item) bool {
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		return a.Name < b.Name
+	},
+	"-price": func(a, b models.Item) bool {
+		if a.Price != b.Price {
+			return a.Price > b.Price
+		}
+		return a.Name < b.Name
+	},
+}
+
+// ListActiveItems возвращает доступные для покупки товары.
+// maxPrice <= 0 означает отсутствие ограничения по цене.
+func (s *MemoryStore) ListActiveItems(maxPrice int, sortKey string) ([]models.Item, error) {
+	less, ok := itemLess[sortKey]
+	if !ok {
+		return nil, fmt.Errorf("unknown item sort %q", sortKey)
+	}
+
+	defer s.lock()()
+
+	items := []models.Item{}
+	for _, item := range s.data.items {
+		if item.Active && (maxPrice <= 0 || item.Price <= maxPrice) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+	return items, nil
+}
+
+// CreateItem добавляет товар в каталог
+func (s *MemoryStore) CreateItem(item *models.Item) error {
+	defer s.lock()()
+
+	if s.data.itemIndex(item.Name) >= 0 {
+		return fmt.Errorf("%w: item %s", ErrDuplicate, item.Name)
+	}
+	s.data.seq.items++
+	item.ID = s.data.seq.items
+	item.Variants = nil
+	s.data.items = append(s.data.items, *item)
+	return nil
+}
+
+// UpdateItem изменяет переданные (не nil) поля товара, включая неактивные товары
+func (s *MemoryStore) UpdateItem(name string, price *int, description *string, active *bool) (models.Item, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 {
+		return models.Item{}, ErrNotFound
+	}
+	item := &s.data.items[i]
+	if price != nil {
+		item.Price = *price
+	}
+	if description != nil {
+		item.Description = *description
+	}
+	if active != nil {
+		item.Active = *active
+	}
+	return *item, nil
+}
+
+// DeactivateItem снимает товар с продажи, возвращает false, если товара нет в каталоге
+func (s *MemoryStore) DeactivateItem(name string) (bool, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 {
+		return false, nil
+	}
+	s.data.items[i].Active = false
+	return true, nil
+}
+
+// DecrementStock списывает товар со склада в рамках транзакции покупки.
+// Товары без учета остатков (Stock == nil) не ограничены.
+func (s *MemoryStore) DecrementStock(itemID uint, amount int) (bool, error) {
+	defer s.lock()()
+
+	for i := range s.data.items {
+		item := &s.data.items[i]
+		if item.ID != itemID {
+			continue
+		}
+		if item.Stock == nil {
+			return true, nil
+		}
+		if *item.Stock < amount {
+			return false, nil
+		}
+		item.Stock = intPtr(*item.Stock - amount)
+		return true, nil
+	}
+	return false, nil
+}
+
+// RestockItem пополняет остаток товара и записывает операцию в журнал пополнений
+func (s *MemoryStore) RestockItem(name string, quantity int, admin string) (models.Item, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 {
+		return models.Item{}, ErrNotFound
+	}
+	item := &s.data.items[i]
+	stock := quantity
+	if item.Stock != nil {
+		stock += *item.Stock
+	}
+	item.Stock = intPtr(stock)
+	s.data.restocks = append(s.data.restocks, restock{ItemID: item.ID, Quantity: quantity, Admin: admin, CreatedAt: memoryNow()})
+	return *item, nil
+}
+
+// GetItemVariants возвращает варианты товара
+func (s *MemoryStore) GetItemVariants(itemID uint) ([]models.ItemVariant, error) {
+	return s.ListVariantsForItems([]uint{itemID})
+}
+
+// ListVariantsForItems возвращает варианты сразу для нескольких товаров
+func (s *MemoryStore) ListVariantsForItems(itemIDs []uint) ([]models.ItemVariant, error) {
+	defer s.lock()()
+
+	wanted := make(map[uint]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		wanted[id] = true
+	}
+	variants := []models.ItemVariant{}
+	for _, v := range s.data.variants {
+		if wanted[v.ItemID] {
+			variants = append(variants, v)
+		}
+	}
+	return variants, nil
+}
+
+// CreateVariant добавляет вариант товара
+func (s *MemoryStore) CreateVariant(variant *models.ItemVariant) error {
+	defer s.lock()()
+
+	for _, v := range s.data.variants {
+		if v.ItemID == variant.ItemID && v.Size == variant.Size && v.Color == variant.Color {
+			return fmt.Errorf("%w: variant %s", ErrDuplicate, variant.Label())
+		}
+	}
+	s.data.seq.variants++
+	variant.ID = s.data.seq.variants
+	s.data.variants = append(s.data.variants, *variant)
+	return nil
+}
+
+// DecrementVariantStock списывает вариант товара со склада в рамках транзакции покупки
+func (s *MemoryStore) DecrementVariantStock(variantID uint, amount int) (bool, error) {
+	defer s.lock()()
+
+	for i := range s.data.variants {
+		variant := &s.data.variants[i]
+		if variant.ID != variantID {
+			continue
+		}
+		if variant.Stock == nil {
+			return true, nil
+		}
+		if *variant.Stock < amount {
+			return false, nil
+		}
+		variant.Stock = intPtr(*variant.Stock - amount)
+		return true, nil
+	}
+	return false, nil
+}
+
+// RestockVariant пополняет остаток варианта товара и записывает операцию в журнал пополнений
+func (s *MemoryStore) RestockVariant(name, size, color string, quantity int, admin string) (models.ItemVariant, error) {
+	defer s.lock()()
+
+	i := s.data.itemIndex(name)
+	if i < 0 {
+		return models.ItemVariant{}, ErrNotFound
+	}
+	itemID := s.data.items[i].ID
+	for j := range s.data.variants {
+		variant := &s.data.variants[j]
+		if variant.ItemID != itemID || variant.Size != size || variant.Color != color {
+			continue
+		}
+		stock := quantity
+		if variant.Stock != nil {
+			stock += *variant.Stock
+		}
+		variant.Stock = intPtr(stock)
+		variantID := variant.ID
+		s.data.restocks = append(s.data.restocks, restock{
+			ItemID: itemID, VariantID: &variantID, Quantity: quantity, Admin: admin, CreatedAt: memoryNow(),
+		})
+		return *variant, nil
+	}
+	return models.ItemVariant{}, ErrNotFound
+}
+
+// CreateOrder сохраняет заказ, заполняя ID, статус и время создания
+func (s *MemoryStore) CreateOrder(order *models.Order) error {
+	defer s.lock()()
+
+	s.data.seq.orders++
+	order.ID = s.data.seq.orders
+	order.Status = models.OrderPlaced
+	order.CreatedAt = memoryNow()
+	s.data.orders = append(s.data.orders, *order)
+	return nil
+}
+
+// ListUserOrders возвращает заказы пользователя, новые первыми
+func (s *MemoryStore) ListUserOrders(userID uint) ([]models.Order, error) {
+	defer s.lock()()
+
+	orders := []models.Order{}
+	for i := len(s.data.orders) - 1; i >= 0; i-- {
+		if s.data.orders[i].UserID == userID {
+			orders = append(orders, s.data.orders[i])
+		}
+	}
+	return orders, nil
+}
+
+// orderIndex - позиция заказа по ID, -1 если его нет
+func (d *memoryData) orderIndex(orderID uint) int {
+	for i := range d.orders {
+		if d.orders[i].ID == orderID {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetOrderForUpdate возвращает заказ пользователя с признаком истечения окна отмены
+func (s *MemoryStore) GetOrderForUpdate(orderID, userID uint, window time.Duration) (LockedOrder, error) {
+	defer s.lock()()
+
+	i := s.data.orderIndex(orderID)
+	if i < 0 || s.data.orders[i].UserID != userID {
+		return LockedOrder{}, ErrNotFound
+	}
+	order := s.data.orders[i]
+	return LockedOrder{Order: order, Expired: memoryNow().Sub(order.CreatedAt) > window}, nil
+}
+
+// GetOrderByIDForUpdate возвращает заказ по ID
+func (s *MemoryStore) GetOrderByIDForUpdate(orderID uint) (models.Order, error) {
+	defer s.lock()()
+
+	i := s.data.orderIndex(orderID)
+	if i < 0 {
+		return models.Order{}, ErrNotFound
+	}
+	return s.data.orders[i], nil
+}
+
+// UpdateOrderStatus меняет статус заказа
+func (s *MemoryStore) UpdateOrderStatus(orderID uint, status string) error {
+	defer s.lock()()
+
+	if i := s.data.orderIndex(orderID); i >= 0 {
+		s.data.orders[i].Status = status
+	}
+	return nil
+}
+
+// ListOrdersByStatus возвращает заказы в указанном статусе, старые первыми; пустой статус - все заказы
+func (s *MemoryStore) ListOrdersByStatus(status string) ([]models.Order, error) {
+	defer s.lock()()
+
+	orders := []models.Order{}
+	for _, order := range s.data.orders {
+		if status == "" || order.Status == status {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// CancelOrder переводит заказ в статус cancelled, возвращает товар на склад,
+// списывает его из инвентаря и начисляет монеты обратно, фиксируя возврат и проводку в журнале.
+// Вызывается внутри Atomic, поэтому при ошибке частичные изменения отбрасываются.
+func (s *MemoryStore) CancelOrder(order models.Order) (models.Refund, error) {
+	defer s.lock()()
+
+	d := s.data
+	for _, refund := range d.refunds {
+		if refund.OrderID == order.ID {
+			return models.Refund{}, fmt.Errorf("%w: refund for order %d", ErrDuplicate, order.ID)
+		}
+	}
+
+	i := d.orderIndex(order.ID)
+	if i < 0 {
+		return models.Refund{}, ErrNotFound
+	}
+	d.orders[i].Status = models.OrderCancelled
+
+	username := ""
+	for j := range d.users {
+		if d.users[j].ID == order.UserID {
+			d.users[j].Coins += order.Total
+			username = d.users[j].Username
+		}
+	}
+	if username == "" {
+		return models.Refund{}, ErrNotFound
+	}
+
+	err := d.postLedgerEntries(models.LedgerRefund, models.OrderReference(order.ID),
+		models.Posting{Account: models.AccountShop, Delta: -order.Total},
+		models.Posting{Account: models.UserAccount(username), Delta: order.Total})
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	inventory := d.inventory[:0:0]
+	for _, row := range d.inventory {
+		if row.UserID == order.UserID && row.ItemName == order.ItemName && row.Variant == order.Variant {
+			row.Amount -= order.Quantity
+			if row.Amount <= 0 {
+				continue
+			}
+		}
+		inventory = append(inventory, row)
+	}
+	d.inventory = inventory
+
+	// Товары без учета остатков (Stock == nil) не изменятся; старые заказы без ItemID возвращаются по названию
+	if order.VariantID != nil {
+		for j := range d.variants {
+			if d.variants[j].ID == *order.VariantID && d.variants[j].Stock != nil {
+				d.variants[j].Stock = intPtr(*d.variants[j].Stock + order.Quantity)
+			}
+		}
+	} else {
+		for j := range d.items {
+			matches := d.items[j].Name == order.ItemName
+			if order.ItemID != nil {
+				matches = d.items[j].ID == *order.ItemID
+			}
+			if matches && d.items[j].Stock != nil {
+				d.items[j].Stock = intPtr(*d.items[j].Stock + order.Quantity)
+			}
+		}
+	}
+
+	d.seq.refunds++
+	refund := models.Refund{ID: d.seq.refunds, OrderID: order.ID, UserID: order.UserID, Amount: order.Total, CreatedAt: memoryNow()}
+	d.refunds = append(d.refunds, refund)
+	return refund, nil
+}
+
+// ListCart возвращает позиции корзины пользователя в порядке добавления
+func (s *MemoryStore) ListCart(userID uint) ([]models.CartItem, error) {
+	defer s.lock()()
+
+	items := []models.CartItem{}
+	for _, item := range s.data.cart {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// AddToCart добавляет товар в корзину, увеличивая количество, если такая позиция уже есть
+func (s *MemoryStore) AddToCart(item *models.CartItem) error {
+	defer s.lock()()
+
+	for i := range s.data.cart {
+		line := &s.data.cart[i]
+		if line.UserID == item.UserID && line.ItemName == item.ItemName && line.Size == item.Size && line.Color == item.Color {
+			line.Quantity += item.Quantity
+			*item = *line
+			return nil
+		}
+	}
+	s.data.seq.cart++
+	item.ID = s.data.seq.cart
+	s.data.cart = append(s.data.cart, *item)
+	return nil
+}
+
+// RemoveFromCart удаляет позицию корзины, возвращает false, если позиции нет
+func (s *MemoryStore) RemoveFromCart(userID, cartItemID uint) (bool, error) {
+	defer s.lock()()
+
+	for i, line := range s.data.cart {
+		if line.ID == cartItemID && line.UserID == userID {
+			s.data.cart = append(s.data.cart[:i:i], s.data.cart[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListCartForUpdate возвращает позиции корзины; транзакция и так держит блокировку всего хранилища
+func (s *MemoryStore) ListCartForUpdate(userID uint) ([]models.CartItem, error) {
+	return s.ListCart(userID)
+}
+
+// RemoveCartItems удаляет перечисленные позиции корзины пользователя и возвращает число удаленных
+func (s *MemoryStore) RemoveCartItems(userID uint, cartItemIDs []uint) (int64, error) {
+	defer s.lock()()
+
+	remove := make(map[uint]bool, len(cartItemIDs))
+	for _, id := range cartItemIDs {
+		remove[id] = true
+	}
+	cart := s.data.cart[:0:0]
+	for _, line := range s.data.cart {
+		if line.UserID != userID || !remove[line.ID] {
+			cart = append(cart, line)
+		}
+	}
+	removed := int64(len(s.data.cart) - len(cart))
+	s.data.cart = cart
+	return removed, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"merch-store/models"
+	"time"
+)
+
+// ReserveIdempotencyKey занимает ключ за запросом с токеном token, возвращает false, если ключ уже использован
+func (s *MemoryStore) ReserveIdempotencyKey(username, key, requestHash, token string) (bool, error) {
+	defer s.lock()()
+
+	if s.data.idempotencyIndex(username, key) >= 0 {
+		return false, nil
+	}
+	s.data.idempotency = append(s.data.idempotency, idempotencyRecord{
+		IdempotencyKey: models.IdempotencyKey{Username: username, Key: key, RequestHash: requestHash, CreatedAt: memoryNow()},
+		Token:          token,
+	})
+	return true, nil
+}
+
+// idempotencyIndex - позиция ключа идемпотентности, -1 если его нет
+func (d *memoryData) idempotencyIndex(username, key string) int {
+	for i := range d.idempotency {
+		if d.idempotency[i].Username == username && d.idempotency[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// reservedIdempotencyIndex - позиция ключа, зарезервированного с токеном token, -1 если такого нет
+func (d *memoryData) reservedIdempotencyIndex(username, key, token string) int {
+	i := d.idempotencyIndex(username, key)
+	if i < 0 || d.idempotency[i].Token != token {
+		return -1
+	}
+	return i
+}
+
+// GetIdempotencyKey возвращает ранее сохраненный ключ
+func (s *MemoryStore) GetIdempotencyKey(username, key string) (models.IdempotencyKey, error) {
+	defer s.lock()()
+
+	i := s.data.idempotencyIndex(username, key)
+	if i < 0 {
+		return models.IdempotencyKey{}, ErrNotFound
+	}
+	return s.data.idempotency[i].IdempotencyKey, nil
+}
+
+// CommitIdempotencyKey отмечает операцию по ключу зафиксированной в текущей транзакции
+func (s *MemoryStore) CommitIdempotencyKey(username, key, token string) (bool, error) {
+	defer s.lock()()
+
+	i := s.data.reservedIdempotencyIndex(username, key, token)
+	if i < 0 || s.data.idempotency[i].CommittedAt != nil {
+		return false, nil
+	}
+	now := memoryNow()
+	s.data.idempotency[i].CommittedAt = &now
+	return true, nil
+}
+
+// SaveIdempotentResponse сохраняет ответ на запрос для повторов, если ключ зарезервирован с токеном token
+func (s *MemoryStore) SaveIdempotentResponse(username, key, token string, statusCode int, response []byte) error {
+	defer s.lock()()
+
+	if i := s.data.reservedIdempotencyIndex(username, key, token); i >= 0 {
+		s.data.idempotency[i].StatusCode = intPtr(statusCode)
+		s.data.idempotency[i].Response = append([]byte(nil), response...)
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey освобождает ключ, зарезервированный с токеном token, если операция по нему не зафиксирована
+func (s *MemoryStore) DeleteIdempotencyKey(username, key, token string) error {
+	defer s.lock()()
+
+	if i := s.data.reservedIdempotencyIndex(username, key, token); i >= 0 && s.data.idempotency[i].CommittedAt == nil {
+		s.data.idempotency = append(s.data.idempotency[:i:i], s.data.idempotency[i+1:]...)
+	}
+	return nil
+}
+
+// ReclaimIdempotencyKey заново резервирует с токеном token ключ, запрос по которому не завершился за timeout
+func (s *MemoryStore) ReclaimIdempotencyKey(username, key, token string, timeout time.Duration) (bool, error) {
+	defer s.lock()()
+
+	i := s.data.idempotencyIndex(username, key)
+	if i < 0 {
+		return false, nil
+	}
+	record := &s.data.idempotency[i]
+	if record.StatusCode != nil || record.CommittedAt != nil || memoryNow().Sub(record.CreatedAt) <= timeout {
+		return false, nil
+	}
+	record.Token = token
+	record.CreatedAt = memoryNow()
+	return true, nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет ключи, зарезервированные раньше чем ttl назад
+func (s *MemoryStore) DeleteExpiredIdempotencyKeys(ttl time.Duration) (int64, error) {
+	defer s.lock()()
+
+	now := memoryNow()
+	kept := s.data.idempotency[:0:0]
+	for _, record := range s.data.idempotency {
+		if now.Sub(record.CreatedAt) <= ttl {
+			kept = append(kept, record)
+		}
+	}
+	deleted := int64(len(s.data.idempotency) - len(kept))
+	s.data.idempotency = kept
+	return deleted, nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+	"sort"
+)
+
+// postLedgerEntries - запись сбалансированной проводки без блокировки хранилища
+func (d *memoryData) postLedgerEntries(reason, reference string, postings ...models.Posting) error {
+	sum := 0
+	for _, p := range postings {
+		if p.Delta == 0 {
+			return fmt.Errorf("ledger posting %s %s: zero delta for %s", reason, reference, p.Account)
+		}
+		sum += p.Delta
+	}
+	if sum != 0 {
+		return fmt.Errorf("unbalanced ledger posting %s %s: sum %d", reason, reference, sum)
+	}
+
+	now := memoryNow()
+	for _, p := range postings {
+		d.seq.ledger++
+		d.ledger = append(d.ledger, models.LedgerEntry{
+			ID: d.seq.ledger, Account: p.Account, Delta: p.Delta, Reason: reason, Reference: reference, CreatedAt: now,
+		})
+	}
+	return nil
+}
+
+// PostLedgerEntries записывает сбалансированную проводку: сумма изменений по всем счетам должна быть равна нулю
+func (s *MemoryStore) PostLedgerEntries(reason, reference string, postings ...models.Posting) error {
+	defer s.lock()()
+
+	return s.data.postLedgerEntries(reason, reference, postings...)
+}
+
+// usersByName - пользователи, упорядоченные по имени
+func (d *memoryData) usersByName() []models.User {
+	users := append([]models.User(nil), d.users...)
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+// ListBalanceMismatches возвращает пользователей, у которых баланс не совпадает с журналом проводок
+func (s *MemoryStore) ListBalanceMismatches() ([]BalanceMismatch, error) {
+	defer s.lock()()
+
+	balances := map[string]int{}
+	for _, entry := range s.data.ledger {
+		balances[entry.Account] += entry.Delta
+	}
+
+	mismatches := []BalanceMismatch{}
+	for _, user := range s.data.usersByName() {
+		ledger := balances[models.UserAccount(user.Username)]
+		if user.Coins != ledger {
+			mismatches = append(mismatches, BalanceMismatch{
+				UserID: user.ID, Username: user.Username, Coins: user.Coins, Ledger: ledger, Difference: user.Coins - ledger,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// ListUnbalancedReferences возвращает проводки с ненулевой суммой изменений
+func (s *MemoryStore) ListUnbalancedReferences() ([]UnbalancedReference, error) {
+	defer s.lock()()
+
+	sums := map[string]int{}
+	for _, entry := range s.data.ledger {
+		sums[entry.Reference] += entry.Delta
+	}
+
+	unbalanced := []UnbalancedReference{}
+	for reference, sum := range sums {
+		if sum != 0 {
+			unbalanced = append(unbalanced, UnbalancedReference{Reference: reference, Sum: sum})
+		}
+	}
+	sort.Slice(unbalanced, func(i, j int) bool { return unbalanced[i].Reference < unbalanced[j].Reference })
+	return unbalanced, nil
+}
+
+// LockReconciliation ничего не делает: транзакции хранилища в памяти выполняются под мьютексом
+func (s *MemoryStore) LockReconciliation() error {
+	return nil
+}
+
+// CreateBalanceAdjustment записывает корректировку, которой сверка привела users.coins к журналу
+func (s *MemoryStore) CreateBalanceAdjustment(userID uint, amount int, note string) (uint, error) {
+	defer s.lock()()
+
+	if amount == 0 {
+		return 0, fmt.Errorf("balance adjustment for user %d: zero amount", userID)
+	}
+	s.data.seq.adjustments++
+	s.data.adjustments = append(s.data.adjustments, balanceAdjustment{
+		ID: s.data.seq.adjustments, UserID: userID, Amount: amount, Note: note, CreatedAt: memoryNow(),
+	})
+	return s.data.seq.adjustments, nil
+}
@@ -0,0 +1,363 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// restock - запись журнала пополнений склада
+type restock struct {
+	ItemID    uint
+	VariantID *uint
+	Quantity  int
+	Admin     string
+	CreatedAt time.Time
+}
+
+// idempotencyRecord - ключ идемпотентности и токен, с которым он зарезервирован
+type idempotencyRecord struct {
+	models.IdempotencyKey
+	Token string
+}
+
+// balanceAdjustment - корректировка баланса по итогам сверки
+type balanceAdjustment struct {
+	ID        uint
+	UserID    uint
+	Amount    int
+	Note      string
+	CreatedAt time.Time
+}
+
+// memorySequences - счетчики идентификаторов, аналог SERIAL
+type memorySequences struct {
+	users, transactions, inventory, items, variants, orders, refunds, cart, ledger, adjustments uint
+}
+
+// memoryData - содержимое хранилища в памяти.
+// Записи хранятся по значению, поля-указатели (остатки, variant_id) при изменении заменяются, а не меняются на месте,
+// поэтому копия, сделанная clone, не зависит от оригинала.
+type memoryData struct {
+	seq          memorySequences
+	users        []models.User
+	transactions []models.Transaction
+	inventory    []models.Inventory
+	items        []models.Item
+	variants     []models.ItemVariant
+	restocks     []restock
+	orders       []models.Order
+	refunds      []models.Refund
+	cart         []models.CartItem
+	idempotency  []idempotencyRecord
+	ledger       []models.LedgerEntry
+	adjustments  []balanceAdjustment
+}
+
+// clone - копия данных для выполнения транзакции
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		seq:          d.seq,
+		users:        append([]models.User(nil), d.users...),
+		transactions: append([]models.Transaction(nil), d.transactions...),
+		inventory:    append([]models.Inventory(nil), d.inventory...),
+		items:        append([]models.Item(nil), d.items...),
+		variants:     append([]models.ItemVariant(nil), d.variants...),
+		restocks:     append([]restock(nil), d.restocks...),
+		orders:       append([]models.Order(nil), d.orders...),
+		refunds:      append([]models.Refund(nil), d.refunds...),
+		cart:         append([]models.CartItem(nil), d.cart...),
+		idempotency:  append([]idempotencyRecord(nil), d.idempotency...),
+		ledger:       append([]models.LedgerEntry(nil), d.ledger...),
+		adjustments:  append([]balanceAdjustment(nil), d.adjustments...),
+	}
+}
+
+// MemoryStore - реализация Store в памяти процесса для демонстраций и тестов.
+// Все операции сериализуются одной блокировкой; Atomic выполняется над копией данных,
+// которая заменяет исходные данные только при успешном завершении.
+type MemoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	inTx bool
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore создает пустое хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mu: &sync.Mutex{}, data: &memoryData{}}
+}
+
+func (s *MemoryStore) Users() UserRepository                  { return s }
+func (s *MemoryStore) Transactions() TransactionRepository    { return s }
+func (s *MemoryStore) Inventory() InventoryRepository         { return s }
+func (s *MemoryStore) Items() ItemRepository                  { return s }
+func (s *MemoryStore) Orders() OrderRepository                { return s }
+func (s *MemoryStore) Carts() CartRepository                  { return s }
+func (s *MemoryStore) IdempotencyKeys() IdempotencyRepository { return s }
+func (s *MemoryStore) Ledger() LedgerRepository               { return s }
+
+// Atomic выполняет fn над копией данных и фиксирует ее, если fn не вернула ошибку
+func (s *MemoryStore) Atomic(fn func(tx Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &MemoryStore{mu: s.mu, data: s.data.clone(), inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*s.data = *tx.data
+	return nil
+}
+
+// lock блокирует хранилище вне транзакции и возвращает функцию разблокировки
+func (s *MemoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// memoryNow - текущее время с точностью PostgreSQL TIMESTAMP
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// intPtr - указатель на копию значения
+func intPtr(v int) *int {
+	return &v
+}
+
+// userIndex - позиция пользователя по имени, -1 если его нет
+func (d *memoryData) userIndex(name string) int {
+	for i := range d.users {
+		if d.users[i].Username == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetUserByName возвращает пользователя по имени
+func (s *MemoryStore) GetUserByName(name string) (models.User, error) {
+	defer s.lock()()
+
+	i := s.data.userIndex(name)
+	if i < 0 {
+		return models.User{}, ErrNotFound
+	}
+	return s.data.users[i], nil
+}
+
+// CreateUser создает пользователя с указанным балансом и заполняет его ID и роль
+func (s *MemoryStore) CreateUser(user *models.User) error {
+	defer s.lock()()
+
+	if s.data.userIndex(user.Username) >= 0 {
+		return fmt.Errorf("%w: user %s", ErrDuplicate, user.Username)
+	}
+	if user.Coins < 0 {
+		return fmt.Errorf("user %s: coins must not be negative", user.Username)
+	}
+
+	s.data.seq.users++
+	user.ID = s.data.seq.users
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	s.data.users = append(s.data.users, *user)
+	return nil
+}
+
+// LockUsers ничего не делает: транзакция держит блокировку всего хранилища
+func (s *MemoryStore) LockUsers(names ...string) error { return nil }
+
+// DebitCoins списывает монеты, только если на балансе их достаточно
+func (s *MemoryStore) DebitCoins(username string, amount int) (bool, error) {
+	defer s.lock()()
+
+	i := s.data.userIndex(username)
+	if i < 0 || s.data.users[i].Coins < amount {
+		return false, nil
+	}
+	s.data.users[i].Coins -= amount
+	return true, nil
+}
+
+// CreditCoins начисляет монеты пользователю, возвращает false, если пользователя нет
+func (s *MemoryStore) CreditCoins(username string, amount int) (bool, error) {
+	defer s.lock()()
+
+	i := s.data.userIndex(username)
+	if i < 0 {
+		return false, nil
+	}
+	s.data.users[i].Coins += amount
+	return true, nil
+}
+
+// CreateTransaction записывает перевод монет и заполняет id и created_at
+func (s *MemoryStore) CreateTransaction(transaction *models.Transaction) error {
+	defer s.lock()()
+
+	if transaction.Amount <= 0 {
+		return fmt.Errorf("transaction amount must be positive")
+	}
+	s.data.seq.transactions++
+	transaction.ID = s.data.seq.transactions
+	transaction.CreatedAt = memoryNow()
+	s.data.transactions = append(s.data.transactions, *transaction)
+	return nil
+}
+
+// newestTransactionsFirst - сортировка переводов от новых к старым
+func newestTransactionsFirst(transactions []models.Transaction) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+		}
+		return transactions[i].ID > transactions[j].ID
+	})
+}
+
+// ListUserTransactions возвращает все переводы пользователя, новые первыми
+func (s *MemoryStore) ListUserTransactions(username string) ([]models.Transaction, error) {
+	defer s.lock()()
+
+	transactions := []models.Transaction{}
+	for _, t := range s.data.transactions {
+		if t.FromUser == username || t.ToUser == username {
+			transactions = append(transactions, t)
+		}
+	}
+	newestTransactionsFirst(transactions)
+	return transactions, nil
+}
+
+// matches - проверка перевода по условиям фильтра, кроме ключа страницы
+func (f TransactionFilter) matches(t models.Transaction) bool {
+	sent := t.FromUser == f.Username
+	received := t.ToUser == f.Username
+	switch f.Direction {
+	case "sent":
+		if !sent || (f.Counterparty != "" && t.ToUser != f.Counterparty) {
+			return false
+		}
+	case "received":
+		if !received || (f.Counterparty != "" && t.FromUser != f.Counterparty) {
+			return false
+		}
+	default:
+		if !sent && !received {
+			return false
+		}
+		if f.Counterparty != "" && !(sent && t.ToUser == f.Counterparty) && !(received && t.FromUser == f.Counterparty) {
+			return false
+		}
+	}
+	if f.From != nil && t.CreatedAt.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && !t.CreatedAt.Before(*f.To) {
+		return false
+	}
+	return true
+}
+
+// ListTransactions возвращает страницу истории переводов по фильтру, новые первыми
+func (s *MemoryStore) ListTransactions(filter TransactionFilter) ([]models.Transaction, error) {
+	defer s.lock()()
+
+	transactions := []models.Transaction{}
+	for _, t := range s.data.transactions {
+		if !filter.matches(t) {
+			continue
+		}
+		// Ключ страницы: (created_at, id) < (AfterCreatedAt, AfterID)
+		if filter.AfterCreatedAt != nil {
+			after := *filter.AfterCreatedAt
+			if t.CreatedAt.After(after) || (t.CreatedAt.Equal(after) && t.ID >= filter.AfterID) {
+				continue
+			}
+		}
+		transactions = append(transactions, t)
+	}
+	newestTransactionsFirst(transactions)
+	if len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+	}
+	return transactions, nil
+}
+
+// SumTransactionsByCounterparty возвращает итоги переводов пользователя по участникам, крупные первыми
+func (s *MemoryStore) SumTransactionsByCounterparty(username string) ([]CounterpartyTotal, error) {
+	defer s.lock()()
+
+	type key struct{ direction, counterparty string }
+	index := map[key]int{}
+	totals := []CounterpartyTotal{}
+	for _, t := range s.data.transactions {
+		var k key
+		switch {
+		case t.FromUser == username:
+			k = key{"sent", t.ToUser}
+		case t.ToUser == username:
+			k = key{"received", t.FromUser}
+		default:
+			continue
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, CounterpartyTotal{Direction: k.direction, Counterparty: k.counterparty})
+		}
+		totals[i].Total += t.Amount
+		totals[i].Count++
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Total != totals[j].Total {
+			return totals[i].Total > totals[j].Total
+		}
+		return totals[i].Counterparty < totals[j].Counterparty
+	})
+	return totals, nil
+}
+
+// ListInventory возвращает инвентарь пользователя
+func (s *MemoryStore) ListInventory(userID uint) ([]models.Inventory, error) {
+	defer s.lock()()
+
+	rows := []models.Inventory{}
+	for _, row := range s.data.inventory {
+		if row.UserID == userID {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// AddInventory добавляет товар в инвентарь, увеличивая количество, если он там уже есть
+func (s *MemoryStore) AddInventory(userID uint, itemName, variant string, amount int) error {
+	defer s.lock()()
+
+	for i, row := range s.data.inventory {
+		if row.UserID == userID && row.ItemName == itemName && row.Variant == variant {
+			s.data.inventory[i].Amount += amount
+			return nil
+		}
+	}
+	s.data.seq.inventory++
+	s.data.inventory = append(s.data.inventory, models.Inventory{
+		ID: s.data.seq.inventory, UserID: userID, ItemName: itemName, Variant: variant, Amount: amount,
+	})
+	return nil
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration - версия схемы из пары файлов NNNN_название.up.sql и NNNN_название.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// String - имя миграции в виде NNNN_название
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationState - миграция и время ее применения, AppliedAt == nil, если она не применена
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+	// Missing - миграция применена, но ее файлов нет в этой версии сервиса
+	Missing bool
+}
+
+// Migrator - хранилище с версионной схемой
+type Migrator interface {
+	// MigrateUp применяет все недостающие миграции по возрастанию версий и возвращает примененные
+	MigrateUp() ([]Migration, error)
+	// MigrateDown откатывает steps последних примененных миграций и возвращает откаченные
+	MigrateDown(steps int) ([]Migration, error)
+	// MigrationStatus возвращает известные и примененные миграции по возрастанию версий
+	MigrationStatus() ([]MigrationState, error)
+}
+
+// migrationFile - имя файла миграции: версия, название и направление
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// loadMigrations читает миграции из files, упорядоченные по версии; у каждой версии должны быть оба файла
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		parts := migrationFile.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(parts[1])
+		data, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d: names %s and %s differ", version, migration.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			migration.up = string(data)
+		} else {
+			migration.down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %s: both up and down files are required", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedMigration - запись таблицы schema_migrations
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// migrator - применение миграций из files к базе данных.
+// Каждая миграция выполняется в своей транзакции вместе с записью в schema_migrations.
+type migrator struct {
+	db    *sqlx.DB
+	files fs.FS
+	// createTable - создание schema_migrations на диалекте базы
+	createTable string
+	// lock не дает другим экземплярам сервиса применять миграции одновременно, nil - без блокировки
+	lock func(conn *sqlx.Conn) (unlock func(), err error)
+}
+
+// locked выполняет fn на отдельном соединении под блокировкой миграций
+func (m *migrator) locked(fn func(conn *sqlx.Conn, migrations []Migration, applied []appliedMigration) error) error {
+	migrations, err := loadMigrations(m.files)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock != nil {
+		unlock, err := m.lock(conn)
+		if err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		defer unlock()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.createTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	applied := []appliedMigration{}
+	if err := conn.SelectContext(ctx, &applied, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version"); err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+// step применяет (up) или откатывает миграцию в транзакции.
+// Версия перепроверяется внутри транзакции: ее мог успеть применить или откатить другой экземпляр.
+func (m *migrator) step(conn *sqlx.Conn, migration Migration, up bool) (bool, error) {
+	ctx := context.Background()
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.Get(&count, "SELECT COUNT(*) FROM schema_migrations WHERE version=$1", migration.Version); err != nil {
+		return false, err
+	}
+	if (count > 0) == up {
+		return false, nil
+	}
+
+	script := migration.up
+	record, args := "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", []interface{}{migration.Version, migration.Name}
+	if !up {
+		script = migration.down
+		record, args = "DELETE FROM schema_migrations WHERE version=$1", args[:1]
+	}
+	if _, err := tx.Exec(script); err != nil {
+		return false, fmt.Errorf("migration %s: %w", migration, err)
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// up применяет все недостающие миграции
+func (m *migrator) up() ([]Migration, error) {
+	done := []Migration{}
+	err := m.locked(func(conn *sqlx.Conn, migrations []Migration, applied []appliedMigration) error {
+		versions := make(map[int]bool, len(applied))
+		for _, record := range applied {
+			versions[record.Version] = true
+		}
+
+		for _, migration := range migrations {
+			if versions[migration.Version] {
+				continue
+			}
+			ok, err := m.step(conn, migration, true)
+			if err != nil {
+				return err
+			}
+			if ok {
+				log.Println("Применена миграция", migration)
+				done = append(done, migration)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// down откатывает steps последних примененных миграций
+func (m *migrator) down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	done := []Migration{}
+	err := m.locked(func(conn *sqlx.Conn, migrations []Migration, applied []appliedMigration) error {
+		known := make(map[int]Migration, len(migrations))
+		for _, migration := range migrations {
+			known[migration.Version] = migration
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+			migration, ok := known[applied[i].Version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is applied but its files are missing", applied[i].Version, applied[i].Name)
+			}
+			ok, err := m.step(conn, migration, false)
+			if err != nil {
+				return err
+			}
+			if ok {
+				log.Println("Откачена миграция", migration)
+				done = append(done, migration)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// status возвращает известные и примененные миграции
+func (m *migrator) status() ([]MigrationState, error) {
+	states := []MigrationState{}
+	err := m.locked(func(conn *sqlx.Conn, migrations []Migration, applied []appliedMigration) error {
+		appliedAt := make(map[int]*time.Time, len(applied))
+		for i := range applied {
+			appliedAt[applied[i].Version] = &applied[i].AppliedAt
+		}
+
+		for _, migration := range migrations {
+			states = append(states, MigrationState{Migration: migration, AppliedAt: appliedAt[migration.Version]})
+			delete(appliedAt, migration.Version)
+		}
+		for _, record := range applied {
+			if _, missing := appliedAt[record.Version]; missing {
+				at := record.AppliedAt
+				states = append(states, MigrationState{
+					Migration: Migration{Version: record.Version, Name: record.Name}, AppliedAt: &at, Missing: true,
+				})
+			}
+		}
+		sort.SliceStable(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+		return nil
+	})
+	return states, err
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"merch-store/models"
+	"time"
+)
+
+// orderColumns - поля заказа, которые читаются из таблицы orders
+const orderColumns = "id, user_id, item_id, variant_id, item_name, variant, quantity, unit_price, total, status, created_at"
+
+// CreateOrder сохраняет заказ в рамках транзакции покупки, заполняя ID, статус и время создания
+func (s *PostgresStore) CreateOrder(order *models.Order) error {
+	return s.q.Get(order,
+		`INSERT INTO orders (user_id, item_id, variant_id, item_name, variant, quantity, unit_price, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+orderColumns,
+		order.UserID, order.ItemID, order.VariantID, order.ItemName, order.Variant, order.Quantity, order.UnitPrice, order.Total)
+}
+
+// ListUserOrders возвращает заказы пользователя, новые первыми
+func (s *PostgresStore) ListUserOrders(userID uint) ([]models.Order, error) {
+	orders := []models.Order{}
+	err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders WHERE user_id=$1 ORDER BY created_at DESC, id DESC", userID)
+	return orders, err
+}
+
+// LockedOrder - заказ, заблокированный для изменения, с признаком истечения окна отмены
+type LockedOrder struct {
+	models.Order
+	Expired bool `db:"expired"`
+}
+
+// GetOrderForUpdate блокирует заказ пользователя до конца транзакции.
+// Возраст заказа сравнивается с окном отмены на стороне БД, чтобы не зависеть от часового пояса сервера.
+func (s *PostgresStore) GetOrderForUpdate(orderID, userID uint, window time.Duration) (LockedOrder, error) {
+	var order LockedOrder
+	err := s.q.Get(&order,
+		"SELECT "+orderColumns+", NOW() - created_at > $3 * INTERVAL '1 second' AS expired FROM orders WHERE id=$1 AND user_id=$2 FOR UPDATE",
+		orderID, userID, int64(window/time.Second))
+	return order, storeError(err)
+}
+
+// GetOrderByIDForUpdate блокирует заказ до конца транзакции
+func (s *PostgresStore) GetOrderByIDForUpdate(orderID uint) (models.Order, error) {
+	var order models.Order
+	err := s.q.Get(&order, "SELECT "+orderColumns+" FROM orders WHERE id=$1 FOR UPDATE", orderID)
+	return order, storeError(err)
+}
+
+// UpdateOrderStatus меняет статус заказа
+func (s *PostgresStore) UpdateOrderStatus(orderID uint, status string) error {
+	_, err := s.q.Exec("UPDATE orders SET status=$2 WHERE id=$1", orderID, status)
+	return err
+}
+
+// ListOrdersByStatus возвращает заказы в указанном статусе, старые первыми; пустой статус - все заказы
+func (s *PostgresStore) ListOrdersByStatus(status string) ([]models.Order, error) {
+	orders := []models.Order{}
+	if status == "" {
+		err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders ORDER BY created_at, id")
+		return orders, err
+	}
+	err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders WHERE status=$1 ORDER BY created_at, id", status)
+	return orders, err
+}
+
+// CancelOrder переводит заказ в статус cancelled, возвращает товар на склад,
+// списывает его из инвентаря и начисляет монеты обратно, фиксируя возврат в таблице refunds и журнале проводок
+func (s *PostgresStore) CancelOrder(order models.Order) (models.Refund, error) {
+	err := s.UpdateOrderStatus(order.ID, models.OrderCancelled)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	var username string
+	err = s.q.Get(&username, "UPDATE users SET coins = coins + $1 WHERE id = $2 RETURNING name", order.Total, order.UserID)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	err = s.PostLedgerEntries(models.LedgerRefund, models.OrderReference(order.ID),
+		models.Posting{Account: models.AccountShop, Delta: -order.Total},
+		models.Posting{Account: models.UserAccount(username), Delta: order.Total})
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	_, err = s.q.Exec("UPDATE inventory SET amount = amount - $1 WHERE user_id=$2 AND item_name=$3 AND variant=$4",
+		order.Quantity, order.UserID, order.ItemName, order.Variant)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	_, err = s.q.Exec("DELETE FROM inventory WHERE user_id=$1 AND item_name=$2 AND variant=$3 AND amount <= 0",
+		order.UserID, order.ItemName, order.Variant)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	// Товары без учета остатков (stock IS NULL) не изменятся; старые заказы без item_id возвращаются по названию
+	switch {
+	case order.VariantID != nil:
+		_, err = s.q.Exec("UPDATE item_variants SET stock = stock + $1 WHERE id = $2", order.Quantity, *order.VariantID)
+	case order.ItemID != nil:
+		_, err = s.q.Exec("UPDATE items SET stock = stock + $1 WHERE id = $2", order.Quantity, *order.ItemID)
+	default:
+		_, err = s.q.Exec("UPDATE items SET stock = stock + $1 WHERE name = $2", order.Quantity, order.ItemName)
+	}
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	var refund models.Refund
+	err = s.q.Get(&refund,
+		"INSERT INTO refunds (order_id, user_id, amount) VALUES ($1, $2, $3) RETURNING id, order_id, user_id, amount, created_at",
+		order.ID, order.UserID, order.Total)
+	return refund, err
+}
@@ -0,0 +1,18 @@
+package repositories
+
+// reconciliationLockKey - ключ advisory-блокировки, под которой сверки выполняются по очереди
+const reconciliationLockKey = 20250215
+
+// LockReconciliation блокирует сверку до конца транзакции, чтобы одновременные запуски
+// не применяли одну и ту же корректировку дважды
+func (s *PostgresStore) LockReconciliation() error {
+	_, err := s.q.Exec("SELECT pg_advisory_xact_lock($1)", reconciliationLockKey)
+	return err
+}
+
+// CreateBalanceAdjustment записывает корректировку, которой сверка привела users.coins к журналу
+func (s *PostgresStore) CreateBalanceAdjustment(userID uint, amount int, note string) (uint, error) {
+	var id uint
+	err := s.q.Get(&id, "INSERT INTO balance_adjustments (user_id, amount, note) VALUES ($1, $2, $3) RETURNING id", userID, amount, note)
+	return id, err
+}
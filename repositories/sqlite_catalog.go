@@ -0,0 +1,324 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+	"time"
+)
+
+// SeedItems добавляет в каталог товары, которых в нем еще нет; существующие товары не изменяются
+func (s *SqliteStore) SeedItems(items []models.Item) error {
+	for _, item := range items {
+		_, err := s.q.Exec(
+			"INSERT INTO items (name, price, description, stock) VALUES ($1, $2, $3, $4) ON CONFLICT (name) DO NOTHING",
+			item.Name, item.Price, item.Description, item.Stock)
+		if err != nil {
+			return fmt.Errorf("seed item %s: %w", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetItem возвращает товар из каталога, включая снятые с продажи
+func (s *SqliteStore) GetItem(name string) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item, "SELECT "+itemColumns+" FROM items WHERE name=$1", name)
+	return item, sqliteStoreError(err)
+}
+
+// GetActiveItem возвращает товар из каталога, доступный для покупки
+func (s *SqliteStore) GetActiveItem(name string) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item, "SELECT "+itemColumns+" FROM items WHERE name=$1 AND active", name)
+	return item, sqliteStoreError(err)
+}
+
+// ListActiveItems возвращает доступные для покупки товары.
+// maxPrice <= 0 означает отсутствие ограничения по цене.
+func (s *SqliteStore) ListActiveItems(maxPrice int, sort string) ([]models.Item, error) {
+	orderBy, ok := itemOrderBy[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown item sort %q", sort)
+	}
+
+	query := "SELECT " + itemColumns + " FROM items WHERE active"
+	args := []interface{}{}
+	if maxPrice > 0 {
+		query += " AND price <= $1"
+		args = append(args, maxPrice)
+	}
+	query += " ORDER BY " + orderBy
+
+	items := []models.Item{}
+	err := s.q.Select(&items, query, args...)
+	return items, err
+}
+
+// CreateItem добавляет товар в каталог
+func (s *SqliteStore) CreateItem(item *models.Item) error {
+	err := s.q.Get(item,
+		"INSERT INTO items (name, price, description, active, stock) VALUES ($1, $2, $3, $4, $5) RETURNING "+itemColumns,
+		item.Name, item.Price, item.Description, item.Active, item.Stock)
+	return sqliteStoreError(err)
+}
+
+// UpdateItem изменяет переданные (не nil) поля товара, включая неактивные товары
+func (s *SqliteStore) UpdateItem(name string, price *int, description *string, active *bool) (models.Item, error) {
+	var item models.Item
+	err := s.q.Get(&item,
+		`UPDATE items SET price = COALESCE($2, price), description = COALESCE($3, description), active = COALESCE($4, active)
+		WHERE name=$1 RETURNING `+itemColumns,
+		name, price, description, active)
+	return item, sqliteStoreError(err)
+}
+
+// DeactivateItem снимает товар с продажи, возвращает false, если товара нет в каталоге
+func (s *SqliteStore) DeactivateItem(name string) (bool, error) {
+	result, err := s.q.Exec("UPDATE items SET active = FALSE WHERE name=$1", name)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DecrementStock списывает товар со склада, false если остатка недостаточно.
+// Товары без учета остатков (stock IS NULL) не ограничены.
+func (s *SqliteStore) DecrementStock(itemID uint, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE items SET stock = stock - $1 WHERE id = $2 AND (stock IS NULL OR stock >= $1)", amount, itemID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// RestockItem пополняет остаток товара и записывает операцию в журнал пополнений.
+// Для товара без учета остатков первое пополнение включает учет.
+func (s *SqliteStore) RestockItem(name string, quantity int, admin string) (models.Item, error) {
+	var item models.Item
+	err := s.Atomic(func(tx Store) error {
+		q := tx.(*SqliteStore).q
+		err := q.Get(&item, "UPDATE items SET stock = COALESCE(stock, 0) + $2 WHERE name=$1 RETURNING "+itemColumns, name, quantity)
+		if err != nil {
+			return sqliteStoreError(err)
+		}
+
+		_, err = q.Exec("INSERT INTO restocks (item_id, quantity, admin) VALUES ($1, $2, $3)", item.ID, quantity, admin)
+		return err
+	})
+	return item, err
+}
+
+// GetItemVariants возвращает варианты товара
+func (s *SqliteStore) GetItemVariants(itemID uint) ([]models.ItemVariant, error) {
+	variants := []models.ItemVariant{}
+	err := s.q.Select(&variants, "SELECT "+variantColumns+" FROM item_variants WHERE item_id=$1 ORDER BY id", itemID)
+	return variants, err
+}
+
+// ListVariantsForItems возвращает варианты сразу для нескольких товаров
+func (s *SqliteStore) ListVariantsForItems(itemIDs []uint) ([]models.ItemVariant, error) {
+	variants := []models.ItemVariant{}
+	if len(itemIDs) == 0 {
+		return variants, nil
+	}
+
+	args := make([]interface{}, len(itemIDs))
+	for i, id := range itemIDs {
+		args[i] = id
+	}
+	err := s.q.Select(&variants,
+		"SELECT "+variantColumns+" FROM item_variants WHERE item_id IN ("+sqlitePlaceholders(1, len(args))+") ORDER BY id", args...)
+	return variants, err
+}
+
+// CreateVariant добавляет вариант товара
+func (s *SqliteStore) CreateVariant(variant *models.ItemVariant) error {
+	err := s.q.Get(variant,
+		"INSERT INTO item_variants (item_id, size, color, stock) VALUES ($1, $2, $3, $4) RETURNING "+variantColumns,
+		variant.ItemID, variant.Size, variant.Color, variant.Stock)
+	return sqliteStoreError(err)
+}
+
+// DecrementVariantStock списывает вариант товара со склада, false если остатка недостаточно
+func (s *SqliteStore) DecrementVariantStock(variantID uint, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE item_variants SET stock = stock - $1 WHERE id = $2 AND (stock IS NULL OR stock >= $1)", amount, variantID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// RestockVariant пополняет остаток варианта товара и записывает операцию в журнал пополнений
+func (s *SqliteStore) RestockVariant(name, size, color string, quantity int, admin string) (models.ItemVariant, error) {
+	var variant models.ItemVariant
+	err := s.Atomic(func(tx Store) error {
+		q := tx.(*SqliteStore).q
+		err := q.Get(&variant,
+			`UPDATE item_variants SET stock = COALESCE(stock, 0) + $4
+			WHERE item_id = (SELECT id FROM items WHERE name=$1) AND size=$2 AND color=$3 RETURNING `+variantColumns,
+			name, size, color, quantity)
+		if err != nil {
+			return sqliteStoreError(err)
+		}
+
+		_, err = q.Exec("INSERT INTO restocks (item_id, variant_id, quantity, admin) VALUES ($1, $2, $3, $4)",
+			variant.ItemID, variant.ID, quantity, admin)
+		return err
+	})
+	return variant, err
+}
+
+// CreateOrder сохраняет заказ, заполняя ID, статус и время создания
+func (s *SqliteStore) CreateOrder(order *models.Order) error {
+	return s.q.Get(order,
+		`INSERT INTO orders (user_id, item_id, variant_id, item_name, variant, quantity, unit_price, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+orderColumns,
+		order.UserID, order.ItemID, order.VariantID, order.ItemName, order.Variant, order.Quantity, order.UnitPrice, order.Total)
+}
+
+// ListUserOrders возвращает заказы пользователя, новые первыми
+func (s *SqliteStore) ListUserOrders(userID uint) ([]models.Order, error) {
+	orders := []models.Order{}
+	err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders WHERE user_id=$1 ORDER BY created_at DESC, id DESC", userID)
+	return orders, err
+}
+
+// GetOrderForUpdate возвращает заказ пользователя и отмечает, истекло ли окно отмены.
+// SQLite блокирует всю базу на время транзакции, поэтому отдельная блокировка строки не нужна.
+func (s *SqliteStore) GetOrderForUpdate(orderID, userID uint, window time.Duration) (LockedOrder, error) {
+	var order LockedOrder
+	err := s.q.Get(&order,
+		"SELECT "+orderColumns+", created_at < strftime('%Y-%m-%d %H:%M:%f000', 'now', printf('-%d seconds', $3)) AS expired FROM orders WHERE id=$1 AND user_id=$2",
+		orderID, userID, int64(window/time.Second))
+	return order, sqliteStoreError(err)
+}
+
+// GetOrderByIDForUpdate возвращает заказ в рамках транзакции
+func (s *SqliteStore) GetOrderByIDForUpdate(orderID uint) (models.Order, error) {
+	var order models.Order
+	err := s.q.Get(&order, "SELECT "+orderColumns+" FROM orders WHERE id=$1", orderID)
+	return order, sqliteStoreError(err)
+}
+
+// UpdateOrderStatus меняет статус заказа
+func (s *SqliteStore) UpdateOrderStatus(orderID uint, status string) error {
+	_, err := s.q.Exec("UPDATE orders SET status=$2 WHERE id=$1", orderID, status)
+	return err
+}
+
+// ListOrdersByStatus возвращает заказы в указанном статусе, старые первыми; пустой статус - все заказы
+func (s *SqliteStore) ListOrdersByStatus(status string) ([]models.Order, error) {
+	orders := []models.Order{}
+	if status == "" {
+		err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders ORDER BY created_at, id")
+		return orders, err
+	}
+	err := s.q.Select(&orders, "SELECT "+orderColumns+" FROM orders WHERE status=$1 ORDER BY created_at, id", status)
+	return orders, err
+}
+
+// CancelOrder переводит заказ в статус cancelled, возвращает товар на склад,
+// списывает его из инвентаря и начисляет монеты обратно, фиксируя возврат в таблице refunds и журнале проводок
+func (s *SqliteStore) CancelOrder(order models.Order) (models.Refund, error) {
+	err := s.UpdateOrderStatus(order.ID, models.OrderCancelled)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	var username string
+	err = s.q.Get(&username, "UPDATE users SET coins = coins + $1 WHERE id = $2 RETURNING name", order.Total, order.UserID)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	err = s.PostLedgerEntries(models.LedgerRefund, models.OrderReference(order.ID),
+		models.Posting{Account: models.AccountShop, Delta: -order.Total},
+		models.Posting{Account: models.UserAccount(username), Delta: order.Total})
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	_, err = s.q.Exec("UPDATE inventory SET amount = amount - $1 WHERE user_id=$2 AND item_name=$3 AND variant=$4",
+		order.Quantity, order.UserID, order.ItemName, order.Variant)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	_, err = s.q.Exec("DELETE FROM inventory WHERE user_id=$1 AND item_name=$2 AND variant=$3 AND amount <= 0",
+		order.UserID, order.ItemName, order.Variant)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	// Товары без учета остатков (stock IS NULL) не изменятся; старые заказы без item_id возвращаются по названию
+	switch {
+	case order.VariantID != nil:
+		_, err = s.q.Exec("UPDATE item_variants SET stock = stock + $1 WHERE id = $2", order.Quantity, *order.VariantID)
+	case order.ItemID != nil:
+		_, err = s.q.Exec("UPDATE items SET stock = stock + $1 WHERE id = $2", order.Quantity, *order.ItemID)
+	default:
+		_, err = s.q.Exec("UPDATE items SET stock = stock + $1 WHERE name = $2", order.Quantity, order.ItemName)
+	}
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	var refund models.Refund
+	err = s.q.Get(&refund,
+		"INSERT INTO refunds (order_id, user_id, amount) VALUES ($1, $2, $3) RETURNING id, order_id, user_id, amount, created_at",
+		order.ID, order.UserID, order.Total)
+	return refund, err
+}
+
+// ListCart возвращает позиции корзины пользователя в порядке добавления
+func (s *SqliteStore) ListCart(userID uint) ([]models.CartItem, error) {
+	items := []models.CartItem{}
+	err := s.q.Select(&items, "SELECT "+cartColumns+" FROM cart_items WHERE user_id=$1 ORDER BY id", userID)
+	return items, err
+}
+
+// AddToCart добавляет товар в корзину, увеличивая количество, если такая позиция уже есть
+func (s *SqliteStore) AddToCart(item *models.CartItem) error {
+	return s.q.Get(item,
+		`INSERT INTO cart_items (user_id, item_name, size, color, quantity) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, item_name, size, color) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+		RETURNING `+cartColumns,
+		item.UserID, item.ItemName, item.Size, item.Color, item.Quantity)
+}
+
+// RemoveFromCart удаляет позицию корзины, возвращает false, если позиции нет
+func (s *SqliteStore) RemoveFromCart(userID, cartItemID uint) (bool, error) {
+	result, err := s.q.Exec("DELETE FROM cart_items WHERE id=$1 AND user_id=$2", cartItemID, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ListCartForUpdate возвращает позиции корзины в рамках транзакции.
+// SQLite блокирует всю базу на время транзакции, поэтому отдельная блокировка строк не нужна.
+func (s *SqliteStore) ListCartForUpdate(userID uint) ([]models.CartItem, error) {
+	return s.ListCart(userID)
+}
+
+// RemoveCartItems удаляет перечисленные позиции корзины пользователя и возвращает число удаленных
+func (s *SqliteStore) RemoveCartItems(userID uint, cartItemIDs []uint) (int64, error) {
+	if len(cartItemIDs) == 0 {
+		return 0, nil
+	}
+
+	args := make([]interface{}, 0, len(cartItemIDs)+1)
+	args = append(args, userID)
+	for _, id := range cartItemIDs {
+		args = append(args, id)
+	}
+	result, err := s.q.Exec(
+		"DELETE FROM cart_items WHERE user_id=$1 AND id IN ("+sqlitePlaceholders(2, len(cartItemIDs))+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
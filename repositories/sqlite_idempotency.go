@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"merch-store/models"
+	"time"
+)
+
+// ReserveIdempotencyKey занимает ключ за запросом с токеном token, возвращает false, если ключ уже использован
+func (s *SqliteStore) ReserveIdempotencyKey(username, key, requestHash, token string) (bool, error) {
+	result, err := s.q.Exec(
+		"INSERT INTO idempotency_keys (username, key, request_hash, token) VALUES ($1, $2, $3, $4) ON CONFLICT (username, key) DO NOTHING",
+		username, key, requestHash, token)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetIdempotencyKey возвращает ранее сохраненный ключ
+func (s *SqliteStore) GetIdempotencyKey(username, key string) (models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := s.q.Get(&record,
+		"SELECT username, key, request_hash, status_code, response, created_at, committed_at FROM idempotency_keys WHERE username=$1 AND key=$2",
+		username, key)
+	return record, sqliteStoreError(err)
+}
+
+// CommitIdempotencyKey отмечает операцию по ключу зафиксированной в текущей транзакции
+func (s *SqliteStore) CommitIdempotencyKey(username, key, token string) (bool, error) {
+	result, err := s.q.Exec(
+		"UPDATE idempotency_keys SET committed_at = "+sqliteNow+" WHERE username=$1 AND key=$2 AND token=$3 AND committed_at IS NULL",
+		username, key, token)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// SaveIdempotentResponse сохраняет ответ на запрос для повторов, если ключ зарезервирован с токеном token
+func (s *SqliteStore) SaveIdempotentResponse(username, key, token string, statusCode int, response []byte) error {
+	_, err := s.q.Exec("UPDATE idempotency_keys SET status_code=$4, response=$5 WHERE username=$1 AND key=$2 AND token=$3",
+		username, key, token, statusCode, response)
+	return err
+}
+
+// DeleteIdempotencyKey освобождает ключ, зарезервированный с токеном token, если операция по нему не зафиксирована
+func (s *SqliteStore) DeleteIdempotencyKey(username, key, token string) error {
+	_, err := s.q.Exec("DELETE FROM idempotency_keys WHERE username=$1 AND key=$2 AND token=$3 AND committed_at IS NULL",
+		username, key, token)
+	return err
+}
+
+// ReclaimIdempotencyKey заново резервирует с токеном token ключ, запрос по которому не завершился за timeout
+func (s *SqliteStore) ReclaimIdempotencyKey(username, key, token string, timeout time.Duration) (bool, error) {
+	result, err := s.q.Exec(
+		"UPDATE idempotency_keys SET token = $3, created_at = "+sqliteNow+" WHERE username=$1 AND key=$2 AND status_code IS NULL AND committed_at IS NULL AND created_at < strftime('%Y-%m-%d %H:%M:%f000', 'now', printf('-%d seconds', $4))",
+		username, key, token, int64(timeout/time.Second))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteExpiredIdempotencyKeys удаляет ключи, зарезервированные раньше чем ttl назад
+func (s *SqliteStore) DeleteExpiredIdempotencyKeys(ttl time.Duration) (int64, error) {
+	result, err := s.q.Exec(
+		"DELETE FROM idempotency_keys WHERE created_at < strftime('%Y-%m-%d %H:%M:%f000', 'now', printf('-%d seconds', $1))",
+		int64(ttl/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"fmt"
+	"merch-store/models"
+)
+
+// PostLedgerEntries записывает сбалансированную проводку: сумма изменений по всем счетам должна быть равна нулю
+func (s *SqliteStore) PostLedgerEntries(reason, reference string, postings ...models.Posting) error {
+	var sum int64
+	values := ""
+	args := []interface{}{reason, reference}
+	for i, p := range postings {
+		sum += int64(p.Delta)
+		if i > 0 {
+			values += ", "
+		}
+		values += "(" + sqlitePlaceholders(len(args)+1, 2) + ", $1, $2)"
+		args = append(args, p.Account, p.Delta)
+	}
+	if sum != 0 {
+		return fmt.Errorf("unbalanced ledger posting %s %s: sum %d", reason, reference, sum)
+	}
+	if len(postings) == 0 {
+		return nil
+	}
+
+	_, err := s.q.Exec("INSERT INTO ledger_entries (account, delta, reason, reference) VALUES "+values, args...)
+	return err
+}
+
+// ListBalanceMismatches возвращает пользователей, у которых баланс не совпадает с журналом проводок
+func (s *SqliteStore) ListBalanceMismatches() ([]BalanceMismatch, error) {
+	mismatches := []BalanceMismatch{}
+	err := s.q.Select(&mismatches, `
+		SELECT u.id, u.name, u.coins, COALESCE(SUM(l.delta), 0) AS ledger, u.coins - COALESCE(SUM(l.delta), 0) AS difference
+		FROM users u LEFT JOIN ledger_entries l ON l.account = 'user:' || u.name
+		GROUP BY u.id, u.name, u.coins
+		HAVING u.coins <> COALESCE(SUM(l.delta), 0)
+		ORDER BY u.name`)
+	return mismatches, err
+}
+
+// ListUnbalancedReferences возвращает проводки с ненулевой суммой изменений
+func (s *SqliteStore) ListUnbalancedReferences() ([]UnbalancedReference, error) {
+	unbalanced := []UnbalancedReference{}
+	err := s.q.Select(&unbalanced,
+		"SELECT reference, SUM(delta) AS sum FROM ledger_entries GROUP BY reference HAVING SUM(delta) <> 0 ORDER BY reference")
+	return unbalanced, err
+}
+
+// LockReconciliation ничего не делает: транзакции SQLite начинаются с BEGIN IMMEDIATE и уже выполняются по очереди
+func (s *SqliteStore) LockReconciliation() error {
+	return nil
+}
+
+// CreateBalanceAdjustment записывает корректировку, которой сверка привела users.coins к журналу
+func (s *SqliteStore) CreateBalanceAdjustment(userID uint, amount int, note string) (uint, error) {
+	var id uint
+	err := s.q.Get(&id, "INSERT INTO balance_adjustments (user_id, amount, note) VALUES ($1, $2, $3) RETURNING id", userID, amount, note)
+	return id, err
+}
@@ -0,0 +1,279 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"merch-store/migrations"
+	"merch-store/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTimeFormat - формат хранения времени в SQLite: строки в нем сравниваются в хронологическом порядке.
+// Значения по умолчанию в migrations/sqlite записываются в том же формате.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+// sqliteNow - текущее время в формате sqliteTimeFormat на стороне БД
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f000', 'now')"
+
+// sqliteTime - время в формате хранения SQLite
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// SqliteStore - реализация Store поверх файла SQLite для запуска без сервера PostgreSQL.
+// Соединение с файлом одно, поэтому запросы и транзакции выполняются по очереди.
+type SqliteStore struct {
+	db *sqlx.DB
+	q  dbtx
+	tx *sqlx.Tx
+}
+
+var (
+	_ Store    = (*SqliteStore)(nil)
+	_ Migrator = (*SqliteStore)(nil)
+)
+
+// ConnectSqlite открывает (или создает) файл базы данных без применения миграций
+func ConnectSqlite(path string) (*SqliteStore, error) {
+	// Транзакции сразу берут блокировку на запись, чтобы другой процесс с тем же файлом
+	// не получил SQLITE_BUSY посреди транзакции; ожидание блокировки ограничено busy_timeout
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sqlx.Connect("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	return &SqliteStore{db: db, q: db}, nil
+}
+
+// OpenSqlite открывает файл базы данных и применяет недостающие миграции
+func OpenSqlite(path string) (*SqliteStore, error) {
+	store, err := ConnectSqlite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := store.MigrateUp(); err != nil {
+		store.db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return store, nil
+}
+
+// DB возвращает соединение с базой данных
+func (s *SqliteStore) DB() *sqlx.DB { return s.db }
+
+func (s *SqliteStore) Users() UserRepository                  { return s }
+func (s *SqliteStore) Transactions() TransactionRepository    { return s }
+func (s *SqliteStore) Inventory() InventoryRepository         { return s }
+func (s *SqliteStore) Items() ItemRepository                  { return s }
+func (s *SqliteStore) Orders() OrderRepository                { return s }
+func (s *SqliteStore) Carts() CartRepository                  { return s }
+func (s *SqliteStore) IdempotencyKeys() IdempotencyRepository { return s }
+func (s *SqliteStore) Ledger() LedgerRepository               { return s }
+
+// Atomic выполняет fn в транзакции базы данных
+func (s *SqliteStore) Atomic(fn func(tx Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SqliteStore{db: s.db, q: tx, tx: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// migrator - миграции из migrations/sqlite. Отдельная блокировка не нужна:
+// транзакции начинаются с BEGIN IMMEDIATE, и каждая миграция перепроверяет версию уже под блокировкой файла.
+func (s *SqliteStore) migrator() *migrator {
+	files, _ := fs.Sub(migrations.Sqlite, "sqlite")
+	return &migrator{
+		db:    s.db,
+		files: files,
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT (` + sqliteNow + `)
+		)`,
+	}
+}
+
+// MigrateUp применяет недостающие миграции
+func (s *SqliteStore) MigrateUp() ([]Migration, error) { return s.migrator().up() }
+
+// MigrateDown откатывает steps последних примененных миграций
+func (s *SqliteStore) MigrateDown(steps int) ([]Migration, error) { return s.migrator().down(steps) }
+
+// MigrationStatus возвращает известные и примененные миграции
+func (s *SqliteStore) MigrationStatus() ([]MigrationState, error) { return s.migrator().status() }
+
+// isSqliteUniqueViolation проверяет, что ошибка вызвана нарушением уникальности или первичного ключа
+func isSqliteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+// sqliteStoreError переводит ошибки SQLite в ошибки хранилища ErrNotFound и ErrDuplicate
+func sqliteStoreError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case isSqliteUniqueViolation(err):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+// sqlitePlaceholders - список параметров $from, $from+1, ... для n значений
+func sqlitePlaceholders(from, n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = "$" + strconv.Itoa(from+i)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// GetUserByName возвращает пользователя по имени
+func (s *SqliteStore) GetUserByName(name string) (models.User, error) {
+	var user models.User
+	err := s.q.Get(&user, "SELECT "+userColumns+" FROM users WHERE name=$1", name)
+	return user, sqliteStoreError(err)
+}
+
+// CreateUser создает пользователя с указанным балансом и заполняет его ID и роль
+func (s *SqliteStore) CreateUser(user *models.User) error {
+	err := s.q.Get(user,
+		"INSERT INTO users (name, password, coins) VALUES ($1, $2, $3) RETURNING "+userColumns,
+		user.Username, user.Password, user.Coins)
+	return sqliteStoreError(err)
+}
+
+// LockUsers ничего не делает: транзакции SQLite начинаются с BEGIN IMMEDIATE и блокируют весь файл
+func (s *SqliteStore) LockUsers(names ...string) error { return nil }
+
+// DebitCoins списывает монеты, только если на балансе их достаточно
+func (s *SqliteStore) DebitCoins(username string, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE users SET coins = coins - $1 WHERE name = $2 AND coins >= $1", amount, username)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CreditCoins начисляет монеты пользователю, возвращает false, если пользователя нет
+func (s *SqliteStore) CreditCoins(username string, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE users SET coins = coins + $1 WHERE name = $2", amount, username)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CreateTransaction записывает перевод монет и заполняет id и created_at
+func (s *SqliteStore) CreateTransaction(transaction *models.Transaction) error {
+	return s.q.Get(transaction,
+		"INSERT INTO transactions (from_user, to_user, amount, message) VALUES ($1, $2, $3, $4) RETURNING "+transactionColumns,
+		transaction.FromUser, transaction.ToUser, transaction.Amount, transaction.Message)
+}
+
+// ListUserTransactions возвращает все переводы пользователя, новые первыми
+func (s *SqliteStore) ListUserTransactions(username string) ([]models.Transaction, error) {
+	transactions := []models.Transaction{}
+	err := s.q.Select(&transactions,
+		"SELECT "+transactionColumns+" FROM transactions WHERE from_user=$1 OR to_user=$1 ORDER BY created_at DESC, id DESC", username)
+	return transactions, err
+}
+
+// ListTransactions возвращает страницу истории переводов по фильтру, новые первыми.
+// Условия совпадают с PostgresStore.ListTransactions, время передается в формате хранения.
+func (s *SqliteStore) ListTransactions(filter TransactionFilter) ([]models.Transaction, error) {
+	args := []interface{}{filter.Username}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var conditions []string
+	switch {
+	case filter.Direction == "sent" && filter.Counterparty != "":
+		conditions = append(conditions, "from_user = $1 AND to_user = "+arg(filter.Counterparty))
+	case filter.Direction == "sent":
+		conditions = append(conditions, "from_user = $1")
+	case filter.Direction == "received" && filter.Counterparty != "":
+		conditions = append(conditions, "to_user = $1 AND from_user = "+arg(filter.Counterparty))
+	case filter.Direction == "received":
+		conditions = append(conditions, "to_user = $1")
+	case filter.Counterparty != "":
+		counterparty := arg(filter.Counterparty)
+		conditions = append(conditions, "((from_user = $1 AND to_user = "+counterparty+") OR (from_user = "+counterparty+" AND to_user = $1))")
+	default:
+		conditions = append(conditions, "(from_user = $1 OR to_user = $1)")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(sqliteTime(*filter.From)))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(sqliteTime(*filter.To)))
+	}
+	if filter.AfterCreatedAt != nil {
+		conditions = append(conditions, "(created_at, id) < ("+arg(sqliteTime(*filter.AfterCreatedAt))+", "+arg(filter.AfterID)+")")
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY created_at DESC, id DESC LIMIT " + arg(filter.Limit)
+
+	transactions := []models.Transaction{}
+	err := s.q.Select(&transactions, query, args...)
+	return transactions, err
+}
+
+// SumTransactionsByCounterparty возвращает итоги переводов пользователя по участникам, крупные первыми
+func (s *SqliteStore) SumTransactionsByCounterparty(username string) ([]CounterpartyTotal, error) {
+	totals := []CounterpartyTotal{}
+	err := s.q.Select(&totals, `
+		SELECT CASE WHEN from_user = $1 THEN 'sent' ELSE 'received' END AS direction,
+			CASE WHEN from_user = $1 THEN to_user ELSE from_user END AS counterparty,
+			SUM(amount) AS total, COUNT(*) AS count
+		FROM transactions WHERE from_user = $1 OR to_user = $1
+		GROUP BY 1, 2 ORDER BY total DESC, counterparty`, username)
+	return totals, err
+}
+
+// ListInventory возвращает инвентарь пользователя
+func (s *SqliteStore) ListInventory(userID uint) ([]models.Inventory, error) {
+	rows := []models.Inventory{}
+	err := s.q.Select(&rows, "SELECT item_name, variant, amount FROM inventory WHERE user_id=$1", userID)
+	return rows, err
+}
+
+// AddInventory добавляет товар в инвентарь, увеличивая количество, если он там уже есть
+func (s *SqliteStore) AddInventory(userID uint, itemName, variant string, amount int) error {
+	_, err := s.q.Exec(
+		"INSERT INTO inventory (user_id, item_name, variant, amount) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, item_name, variant) DO UPDATE SET amount = inventory.amount + EXCLUDED.amount",
+		userID, itemName, variant, amount)
+	return err
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"errors"
+	"merch-store/models"
+	"time"
+)
+
+// Ошибки хранилища, не зависящие от конкретной базы данных
+var (
+	// ErrNotFound - запись не найдена
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate - запись нарушает ограничение уникальности
+	ErrDuplicate = errors.New("duplicate")
+)
+
+// UserRepository - пользователи и их балансы
+type UserRepository interface {
+	// GetUserByName возвращает пользователя по имени, ErrNotFound если его нет
+	GetUserByName(name string) (models.User, error)
+	// CreateUser создает пользователя и заполняет его ID, ErrDuplicate если имя занято
+	CreateUser(user *models.User) error
+	// LockUsers блокирует строки пользователей до конца транзакции в порядке имен,
+	// чтобы встречные операции над одними и теми же пользователями не взаимоблокировались
+	LockUsers(names ...string) error
+	// DebitCoins списывает монеты, только если на балансе их достаточно; false, если монет не хватает
+	DebitCoins(username string, amount int) (bool, error)
+	// CreditCoins начисляет монеты, false, если пользователя нет
+	CreditCoins(username string, amount int) (bool, error)
+}
+
+// TransactionRepository - переводы монет между пользователями
+type TransactionRepository interface {
+	// CreateTransaction записывает перевод и заполняет id и created_at
+	CreateTransaction(transaction *models.Transaction) error
+	// ListUserTransactions возвращает все переводы пользователя, новые первыми
+	ListUserTransactions(username string) ([]models.Transaction, error)
+	// ListTransactions возвращает страницу истории переводов по фильтру, новые первыми
+	ListTransactions(filter TransactionFilter) ([]models.Transaction, error)
+	// SumTransactionsByCounterparty возвращает итоги переводов пользователя по участникам, крупные первыми
+	SumTransactionsByCounterparty(username string) ([]CounterpartyTotal, error)
+}
+
+// InventoryRepository - купленные пользователями товары
+type InventoryRepository interface {
+	// ListInventory возвращает инвентарь пользователя
+	ListInventory(userID uint) ([]models.Inventory, error)
+	// AddInventory добавляет товар в инвентарь, увеличивая количество, если он там уже есть
+	AddInventory(userID uint, itemName, variant string, amount int) error
+}
+
+// ItemRepository - каталог товаров, их варианты и остатки
+type ItemRepository interface {
+	// SeedItems добавляет в каталог товары, которых в нем еще нет; существующие товары не изменяются
+	SeedItems(items []models.Item) error
+	// GetItem возвращает товар по названию, включая снятые с продажи
+	GetItem(name string) (models.Item, error)
+	// GetActiveItem возвращает товар, доступный для покупки
+	GetActiveItem(name string) (models.Item, error)
+	// ListActiveItems возвращает доступные для покупки товары, maxPrice <= 0 - без ограничения по цене.
+	// sort - "name", "-name", "price" или "-price", пустая строка - по названию.
+	ListActiveItems(maxPrice int, sort string) ([]models.Item, error)
+	// CreateItem добавляет товар в каталог, ErrDuplicate если название занято
+	CreateItem(item *models.Item) error
+	// UpdateItem изменяет переданные (не nil) поля товара, включая неактивные товары
+	UpdateItem(name string, price *int, description *string, active *bool) (models.Item, error)
+	// DeactivateItem снимает товар с продажи, возвращает false, если товара нет в каталоге
+	DeactivateItem(name string) (bool, error)
+	// DecrementStock списывает товар со склада, false если остатка недостаточно
+	DecrementStock(itemID uint, amount int) (bool, error)
+	// RestockItem пополняет остаток товара и записывает операцию в журнал пополнений
+	RestockItem(name string, quantity int, admin string) (models.Item, error)
+	// GetItemVariants возвращает варианты товара
+	GetItemVariants(itemID uint) ([]models.ItemVariant, error)
+	// ListVariantsForItems возвращает варианты сразу для нескольких товаров
+	ListVariantsForItems(itemIDs []uint) ([]models.ItemVariant, error)
+	// CreateVariant добавляет вариант товара, ErrDuplicate если такой вариант уже есть
+	CreateVariant(variant *models.ItemVariant) error
+	// DecrementVariantStock списывает вариант товара со склада, false если остатка недостаточно
+	DecrementVariantStock(variantID uint, amount int) (bool, error)
+	// RestockVariant пополняет остаток варианта товара и записывает операцию в журнал пополнений
+	RestockVariant(name, size, color string, quantity int, admin string) (models.ItemVariant, error)
+}
+
+// OrderRepository - заказы и возвраты
+type OrderRepository interface {
+	// CreateOrder сохраняет заказ, заполняя ID, статус и время создания
+	CreateOrder(order *models.Order) error
+	// ListUserOrders возвращает заказы пользователя, новые первыми
+	ListUserOrders(userID uint) ([]models.Order, error)
+	// GetOrderForUpdate блокирует заказ пользователя до конца транзакции и отмечает, истекло ли окно отмены
+	GetOrderForUpdate(orderID, userID uint, window time.Duration) (LockedOrder, error)
+	// GetOrderByIDForUpdate блокирует заказ до конца транзакции
+	GetOrderByIDForUpdate(orderID uint) (models.Order, error)
+	// UpdateOrderStatus меняет статус заказа
+	UpdateOrderStatus(orderID uint, status string) error
+	// ListOrdersByStatus возвращает заказы в указанном статусе, старые первыми; пустой статус - все заказы
+	ListOrdersByStatus(status string) ([]models.Order, error)
+	// CancelOrder отменяет заказ: возвращает товар на склад, списывает его из инвентаря и начисляет монеты обратно
+	CancelOrder(order models.Order) (models.Refund, error)
+}
+
+// CartRepository - корзины пользователей
+type CartRepository interface {
+	// ListCart возвращает позиции корзины пользователя в порядке добавления
+	ListCart(userID uint) ([]models.CartItem, error)
+	// AddToCart добавляет товар в корзину, увеличивая количество, если такая позиция уже есть
+	AddToCart(item *models.CartItem) error
+	// RemoveFromCart удаляет позицию корзины, возвращает false, если позиции нет
+	RemoveFromCart(userID, cartItemID uint) (bool, error)
+	// ListCartForUpdate возвращает позиции корзины и блокирует их до конца транзакции
+	ListCartForUpdate(userID uint) ([]models.CartItem, error)
+	// RemoveCartItems удаляет перечисленные позиции корзины пользователя и возвращает число удаленных
+	RemoveCartItems(userID uint, cartItemIDs []uint) (int64, error)
+}
+
+// IdempotencyRepository - ключи идемпотентности и сохраненные ответы
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey занимает ключ за запросом с токеном token, возвращает false, если ключ уже использован
+	ReserveIdempotencyKey(username, key, requestHash, token string) (bool, error)
+	// GetIdempotencyKey возвращает ранее сохраненный ключ
+	GetIdempotencyKey(username, key string) (models.IdempotencyKey, error)
+	// CommitIdempotencyKey отмечает операцию по ключу зафиксированной; вызывается в транзакции операции.
+	// false, если ключ зарезервирован с другим токеном или уже зафиксирован
+	CommitIdempotencyKey(username, key, token string) (bool, error)
+	// SaveIdempotentResponse сохраняет ответ на запрос для повторов, если ключ зарезервирован с токеном token
+	SaveIdempotentResponse(username, key, token string, statusCode int, response []byte) error
+	// DeleteIdempotencyKey освобождает ключ, зарезервированный с токеном token, чтобы запрос можно было повторить;
+	// ключ зафиксированной операции не освобождается
+	DeleteIdempotencyKey(username, key, token string) error
+	// ReclaimIdempotencyKey заново резервирует с токеном token ключ, запрос по которому не завершился за timeout;
+	// false, если ключ завершен, операция по нему зафиксирована или он зарезервирован недавно
+	ReclaimIdempotencyKey(username, key, token string, timeout time.Duration) (bool, error)
+	// DeleteExpiredIdempotencyKeys удаляет ключи, зарезервированные раньше чем ttl назад, и возвращает их число
+	DeleteExpiredIdempotencyKeys(ttl time.Duration) (int64, error)
+}
+
+// LedgerRepository - журнал проводок и сверка балансов
+type LedgerRepository interface {
+	// PostLedgerEntries записывает сбалансированную проводку: сумма изменений по всем счетам должна быть равна нулю
+	PostLedgerEntries(reason, reference string, postings ...models.Posting) error
+	// ListBalanceMismatches возвращает пользователей, у которых баланс не совпадает с журналом проводок
+	ListBalanceMismatches() ([]BalanceMismatch, error)
+	// ListUnbalancedReferences возвращает проводки с ненулевой суммой изменений
+	ListUnbalancedReferences() ([]UnbalancedReference, error)
+	// LockReconciliation блокирует сверку до конца транзакции, одновременные сверки выполняются по очереди
+	LockReconciliation() error
+	// CreateBalanceAdjustment записывает в balance_adjustments, на сколько сверка изменила users.coins,
+	// и возвращает ID записи; сам баланс меняет вызывающий код
+	CreateBalanceAdjustment(userID uint, amount int, note string) (uint, error)
+}
+
+// Store - хранилище данных магазина.
+// Репозитории, полученные из Store внутри Atomic, работают в одной транзакции.
+type Store interface {
+	Users() UserRepository
+	Transactions() TransactionRepository
+	Inventory() InventoryRepository
+	Items() ItemRepository
+	Orders() OrderRepository
+	Carts() CartRepository
+	IdempotencyKeys() IdempotencyRepository
+	Ledger() LedgerRepository
+	// Atomic выполняет fn в транзакции: при ошибке изменения откатываются, иначе фиксируются.
+	// Вложенный вызов выполняется в уже открытой транзакции.
+	Atomic(fn func(tx Store) error) error
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"merch-store/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const transactionColumns = "id, from_user, to_user, amount, message, created_at"
+
+// CreateTransaction записывает перевод монет в рамках транзакции и заполняет id и created_at
+func (s *PostgresStore) CreateTransaction(transaction *models.Transaction) error {
+	return s.q.Get(transaction,
+		"INSERT INTO transactions (from_user, to_user, amount, message) VALUES ($1, $2, $3, $4) RETURNING "+transactionColumns,
+		transaction.FromUser, transaction.ToUser, transaction.Amount, transaction.Message)
+}
+
+// ListUserTransactions возвращает все переводы пользователя, новые первыми
+func (s *PostgresStore) ListUserTransactions(username string) ([]models.Transaction, error) {
+	transactions := []models.Transaction{}
+	err := s.q.Select(&transactions,
+		"SELECT "+transactionColumns+" FROM transactions WHERE from_user=$1 OR to_user=$1 ORDER BY created_at DESC, id DESC", username)
+	return transactions, err
+}
+
+// TransactionFilter - условия выборки истории переводов пользователя.
+// Направление "sent" или "received", пустое - оба; пустые поля не ограничивают выборку.
+// Выборка идет от новых к старым, AfterCreatedAt/AfterID - ключ последней записи предыдущей страницы.
+type TransactionFilter struct {
+	Username       string
+	Direction      string
+	Counterparty   string
+	From           *time.Time
+	To             *time.Time
+	AfterCreatedAt *time.Time
+	AfterID        uint
+	Limit          int
+}
+
+// ListTransactions возвращает страницу истории переводов по фильтру, новые первыми
+func (s *PostgresStore) ListTransactions(filter TransactionFilter) ([]models.Transaction, error) {
+	args := []interface{}{filter.Username}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var conditions []string
+	switch {
+	case filter.Direction == "sent" && filter.Counterparty != "":
+		conditions = append(conditions, "from_user = $1 AND to_user = "+arg(filter.Counterparty))
+	case filter.Direction == "sent":
+		conditions = append(conditions, "from_user = $1")
+	case filter.Direction == "received" && filter.Counterparty != "":
+		conditions = append(conditions, "to_user = $1 AND from_user = "+arg(filter.Counterparty))
+	case filter.Direction == "received":
+		conditions = append(conditions, "to_user = $1")
+	case filter.Counterparty != "":
+		counterparty := arg(filter.Counterparty)
+		conditions = append(conditions, "((from_user = $1 AND to_user = "+counterparty+") OR (from_user = "+counterparty+" AND to_user = $1))")
+	default:
+		conditions = append(conditions, "(from_user = $1 OR to_user = $1)")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.To))
+	}
+	if filter.AfterCreatedAt != nil {
+		conditions = append(conditions, "(created_at, id) < ("+arg(*filter.AfterCreatedAt)+", "+arg(filter.AfterID)+")")
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY created_at DESC, id DESC LIMIT " + arg(filter.Limit)
+
+	transactions := []models.Transaction{}
+	err := s.q.Select(&transactions, query, args...)
+	return transactions, err
+}
+
+// CounterpartyTotal - сумма и количество переводов пользователя с одним участником в одном направлении
+type CounterpartyTotal struct {
+	Direction    string `db:"direction"`
+	Counterparty string `db:"counterparty"`
+	Total        int    `db:"total"`
+	Count        int    `db:"count"`
+}
+
+// SumTransactionsByCounterparty возвращает итоги переводов пользователя по участникам, крупные первыми
+func (s *PostgresStore) SumTransactionsByCounterparty(username string) ([]CounterpartyTotal, error) {
+	totals := []CounterpartyTotal{}
+	err := s.q.Select(&totals, `
+		SELECT CASE WHEN from_user = $1 THEN 'sent' ELSE 'received' END AS direction,
+			CASE WHEN from_user = $1 THEN to_user ELSE from_user END AS counterparty,
+			SUM(amount) AS total, COUNT(*) AS count
+		FROM transactions WHERE from_user = $1 OR to_user = $1
+		GROUP BY 1, 2 ORDER BY total DESC, counterparty`, username)
+	return totals, err
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"merch-store/models"
+
+	"github.com/lib/pq"
+)
+
+// userColumns - поля пользователя, которые читаются из таблицы users
+const userColumns = "id, name, password, coins, role"
+
+// GetUserByName возвращает пользователя по имени
+func (s *PostgresStore) GetUserByName(name string) (models.User, error) {
+	var user models.User
+	err := s.q.Get(&user, "SELECT "+userColumns+" FROM users WHERE name=$1", name)
+	return user, storeError(err)
+}
+
+// CreateUser создает пользователя с указанным балансом и заполняет его ID и роль
+func (s *PostgresStore) CreateUser(user *models.User) error {
+	err := s.q.Get(user,
+		"INSERT INTO users (name, password, coins) VALUES ($1, $2, $3) RETURNING "+userColumns,
+		user.Username, user.Password, user.Coins)
+	return storeError(err)
+}
+
+// LockUsers блокирует строки пользователей в порядке имен до конца транзакции.
+// Порядок общий для всех транзакций, поэтому встречные переводы A->B и B->A ждут друг друга, а не взаимоблокируются.
+func (s *PostgresStore) LockUsers(names ...string) error {
+	_, err := s.q.Exec("SELECT id FROM users WHERE name = ANY($1) ORDER BY name FOR UPDATE", pq.Array(names))
+	return err
+}
+
+// DebitCoins списывает монеты, только если на балансе их достаточно.
+// Возвращает false, если монет не хватает; строка пользователя остается заблокированной до конца транзакции.
+func (s *PostgresStore) DebitCoins(username string, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE users SET coins = coins - $1 WHERE name = $2 AND coins >= $1", amount, username)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// CreditCoins начисляет монеты пользователю, возвращает false, если пользователя нет
+func (s *PostgresStore) CreditCoins(username string, amount int) (bool, error) {
+	result, err := s.q.Exec("UPDATE users SET coins = coins + $1 WHERE name = $2", amount, username)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
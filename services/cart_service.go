@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"merch-store/models"
+	"merch-store/repositories"
+)
+
+type Cart struct {
+	Items []models.CartItem `json:"items"`
+	Total int               `json:"total"`
+}
+
+// CartService - корзина и оформление заказа
+type CartService struct {
+	store repositories.Store
+}
+
+// NewCartService - создание сервиса корзины
+func NewCartService(store repositories.Store) *CartService {
+	return &CartService{store: store}
+}
+
+// resolveCartItem - поиск товара и варианта для позиции корзины по текущему каталогу
+func resolveCartItem(items repositories.ItemRepository, line models.CartItem) (models.Item, *models.ItemVariant, error) {
+	item, err := items.GetActiveItem(line.ItemName)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Item{}, nil, fmt.Errorf("%s: %w", line.ItemName, ErrItemNotFound)
+	}
+	if err != nil {
+		return models.Item{}, nil, fmt.Errorf("ошибка получения данных товара: %w", err)
+	}
+
+	variant, err := selectVariant(items, item, line.Size, line.Color)
+	if err != nil {
+		return models.Item{}, nil, fmt.Errorf("%s: %w", line.ItemName, err)
+	}
+
+	return item, variant, nil
+}
+
+// GetCart - содержимое корзины с ценами по текущему каталогу.
+// Позиции, снятые с продажи, остаются в корзине с нулевой ценой, чтобы их можно было удалить.
+func (s *CartService) GetCart(username string) (Cart, error) {
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return Cart{}, err
+	}
+
+	lines, err := s.store.Carts().ListCart(user.ID)
+	if err != nil {
+		return Cart{}, fmt.Errorf("error fetching cart: %w", err)
+	}
+
+	cart := Cart{Items: lines}
+	for i := range cart.Items {
+		item, err := s.store.Items().GetActiveItem(cart.Items[i].ItemName)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Cart{}, fmt.Errorf("error fetching item: %w", err)
+		}
+		cart.Items[i].UnitPrice = item.Price
+		cart.Items[i].Total, err = orderCost(item.Price, cart.Items[i].Quantity)
+		if err != nil {
+			return Cart{}, err
+		}
+		cart.Total, err = addCost(cart.Total, cart.Items[i].Total)
+		if err != nil {
+			return Cart{}, err
+		}
+	}
+
+	return cart, nil
+}
+
+// AddToCart - добавление товара в корзину, наличие товара и варианта проверяется сразу.
+// Количество в позиции вместе с уже добавленным не может превышать MaxOrderQuantity.
+func (s *CartService) AddToCart(username, itemName string, amount int, size, color string) (models.CartItem, error) {
+	if amount <= 0 || amount > MaxOrderQuantity {
+		return models.CartItem{}, ErrInvalidQuantity
+	}
+
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+
+	line := models.CartItem{UserID: user.ID, ItemName: itemName, Size: size, Color: color, Quantity: amount}
+	if _, _, err = resolveCartItem(s.store.Items(), line); err != nil {
+		return models.CartItem{}, err
+	}
+
+	err = s.store.Atomic(func(tx repositories.Store) error {
+		err := tx.Carts().AddToCart(&line)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения корзины: %w", err)
+		}
+		if line.Quantity > MaxOrderQuantity {
+			return ErrInvalidQuantity
+		}
+		return nil
+	})
+	if err != nil {
+		return models.CartItem{}, err
+	}
+
+	return line, nil
+}
+
+// RemoveFromCart - удаление позиции из корзины
+func (s *CartService) RemoveFromCart(username string, cartItemID uint) error {
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return err
+	}
+
+	found, err := s.store.Carts().RemoveFromCart(user.ID, cartItemID)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения корзины: %w", err)
+	}
+	if !found {
+		return ErrCartItemNotFound
+	}
+
+	return nil
+}
+
+// Checkout - оформление всей корзины одной транзакцией.
+// Позиции корзины читаются и блокируются внутри транзакции, поэтому параллельное оформление той же корзины
+// дождется ее завершения и увидит корзину пустой. Удаляются только оплаченные позиции: товар,
+// добавленный в корзину во время оформления, остается в ней.
+// Цены, остатки и баланс проверяются для всех позиций; при любой ошибке не списывается ничего.
+func (s *CartService) Checkout(username string) ([]models.Order, error) {
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	err = s.store.Atomic(func(tx repositories.Store) error {
+		lines, err := tx.Carts().ListCartForUpdate(user.ID)
+		if err != nil {
+			return fmt.Errorf("ошибка получения корзины: %w", err)
+		}
+		if len(lines) == 0 {
+			return ErrCartEmpty
+		}
+
+		// Оцениваем все позиции по текущему каталогу
+		items := make([]models.Item, len(lines))
+		variants := make([]*models.ItemVariant, len(lines))
+		lineIDs := make([]uint, len(lines))
+		totalCost := 0
+		for i, line := range lines {
+			items[i], variants[i], err = resolveCartItem(tx.Items(), line)
+			if err != nil {
+				return err
+			}
+			cost, err := orderCost(items[i].Price, line.Quantity)
+			if err != nil {
+				return err
+			}
+			totalCost, err = addCost(totalCost, cost)
+			if err != nil {
+				return err
+			}
+			lineIDs[i] = line.ID
+		}
+
+		debited, err := tx.Users().DebitCoins(username, totalCost)
+		if err != nil {
+			return fmt.Errorf("ошибка обновления баланса: %w", err)
+		}
+		if !debited {
+			return ErrInsufficientFunds
+		}
+
+		orders = make([]models.Order, 0, len(lines))
+		for i, line := range lines {
+			order, err := issueItem(tx, user, items[i], variants[i], line.Quantity)
+			if err != nil {
+				return fmt.Errorf("%s: %w", line.ItemName, err)
+			}
+			orders = append(orders, order)
+		}
+
+		removed, err := tx.Carts().RemoveCartItems(user.ID, lineIDs)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения корзины: %w", err)
+		}
+		if removed != int64(len(lineIDs)) {
+			return ErrCartChanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
@@ -1,33 +1,82 @@
 package services
 
 import (
-	"errors"
+	"fmt"
 	"merch-store/models"
 	"merch-store/repositories"
+	"unicode/utf8"
 )
 
-// SendCoin - бизнес-логика для передачи монет
-func SendCoin(fromUser, toUser string, amount int) error {
-	// Проверяем баланс отправителя
-	var sender models.User
-	err := repositories.DB.Get(&sender, "SELECT coins FROM users WHERE name=$1", fromUser)
-	if err != nil || sender.Coins < amount {
-		return errors.New("недостаточно монет")
+// MaxTransferMessageLength - максимальная длина сообщения к переводу в символах
+const MaxTransferMessageLength = 200
+
+// CoinService - переводы монет и история переводов
+type CoinService struct {
+	store repositories.Store
+}
+
+// NewCoinService - создание сервиса переводов
+func NewCoinService(store repositories.Store) *CoinService {
+	return &CoinService{store: store}
+}
+
+// SendCoin - бизнес-логика для передачи монет с необязательным сообщением получателю.
+// Проверка баланса и списание выполняются одним условным списанием внутри транзакции,
+// поэтому параллельные переводы не могут увести баланс в минус. Строки обоих участников
+// блокируются заранее в порядке имен, поэтому встречные переводы не взаимоблокируются.
+// Ошибки бизнес-правил возвращаются как ErrInsufficientFunds и ErrRecipientNotFound, остальные - ошибки хранилища.
+func (s *CoinService) SendCoin(fromUser, toUser string, amount int, message string) (models.Transaction, error) {
+	if amount <= 0 {
+		return models.Transaction{}, ErrInvalidAmount
+	}
+	if fromUser == toUser {
+		return models.Transaction{}, ErrSelfTransfer
+	}
+	if utf8.RuneCountInString(message) > MaxTransferMessageLength {
+		return models.Transaction{}, ErrMessageTooLong
 	}
 
-	// Проверяем существование получателя
-	var receiver models.User
-	err = repositories.DB.Get(&receiver, "SELECT id FROM users WHERE name=$1", toUser)
+	transaction := models.Transaction{FromUser: fromUser, ToUser: toUser, Amount: amount, Message: message}
+	err := s.store.Atomic(func(tx repositories.Store) error {
+		if err := tx.Users().LockUsers(fromUser, toUser); err != nil {
+			return fmt.Errorf("lock users: %w", err)
+		}
+
+		// Списываем монеты, только если их достаточно
+		debited, err := tx.Users().DebitCoins(fromUser, amount)
+		if err != nil {
+			return fmt.Errorf("debit sender: %w", err)
+		}
+		if !debited {
+			return ErrInsufficientFunds
+		}
+
+		// Начисляем получателю, отсутствие получателя откатывает списание
+		credited, err := tx.Users().CreditCoins(toUser, amount)
+		if err != nil {
+			return fmt.Errorf("credit recipient: %w", err)
+		}
+		if !credited {
+			return ErrRecipientNotFound
+		}
+
+		err = tx.Transactions().CreateTransaction(&transaction)
+		if err != nil {
+			return fmt.Errorf("record transaction: %w", err)
+		}
+
+		err = tx.Ledger().PostLedgerEntries(models.LedgerTransfer, models.TransactionReference(transaction.ID),
+			models.Posting{Account: models.UserAccount(fromUser), Delta: -amount},
+			models.Posting{Account: models.UserAccount(toUser), Delta: amount})
+		if err != nil {
+			return fmt.Errorf("post transfer to ledger: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return errors.New("получатель не найден")
+		return models.Transaction{}, err
 	}
 
-	// Обновляем баланс
-	tx := repositories.DB.MustBegin()
-	tx.MustExec("UPDATE users SET coins = coins - $1 WHERE name = $2", amount, fromUser)
-	tx.MustExec("UPDATE users SET coins = coins + $1 WHERE name = $2", amount, toUser)
-	tx.MustExec("INSERT INTO transactions (from_user, to_user, amount) VALUES ($1, $2, $3)", fromUser, toUser, amount)
-	tx.Commit()
-
-	return nil
+	return transaction, nil
 }
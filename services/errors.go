@@ -0,0 +1,83 @@
+package services
+
+// Error - ошибка бизнес-правила с устойчивым машинно-читаемым кодом.
+// Code не меняется между версиями и используется клиентами, Message - текст для пользователя.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// knownErrors - все объявленные ошибки бизнес-правил, для проверки каталогов сообщений
+var knownErrors []*Error
+
+// newError - создание ошибки бизнес-правила
+func newError(code, message string) *Error {
+	err := &Error{Code: code, Message: message}
+	knownErrors = append(knownErrors, err)
+	return err
+}
+
+// Пользователи и авторизация
+var (
+	ErrUserNotFound = newError("user_not_found", "пользователь не найден")
+	ErrUserExists   = newError("user_exists", "пользователь уже существует")
+	ErrUnauthorized = newError("unauthorized", "неавторизован")
+)
+
+// Перевод монет
+var (
+	ErrInsufficientFunds = newError("insufficient_funds", "недостаточно монет")
+	ErrRecipientNotFound = newError("recipient_not_found", "получатель не найден")
+	ErrInvalidAmount     = newError("invalid_amount", "сумма перевода должна быть положительной")
+	ErrSelfTransfer      = newError("self_transfer", "нельзя переводить монеты самому себе")
+	ErrMessageTooLong    = newError("message_too_long", "сообщение к переводу слишком длинное")
+)
+
+// История переводов
+var (
+	ErrInvalidDirection   = newError("invalid_direction", "направление должно быть sent или received")
+	ErrInvalidCursor      = newError("invalid_cursor", "некорректный курсор")
+	ErrInvalidDateRange   = newError("invalid_date_range", "некорректный период")
+	ErrInvalidLimit       = newError("invalid_limit", "некорректный размер страницы")
+	ErrInvalidHistoryView = newError("invalid_history_view", "coinHistory может быть только grouped")
+)
+
+// Каталог и покупки
+var (
+	ErrItemNotFound     = newError("item_not_found", "товар не найден")
+	ErrItemExists       = newError("item_exists", "товар уже существует")
+	ErrInvalidItemPrice = newError("invalid_item_price", "цена товара должна быть положительной")
+	ErrInvalidItemSort  = newError("invalid_sort", "некорректная сортировка")
+	ErrInvalidQuantity  = newError("invalid_quantity", "количество должно быть от 1 до 1000")
+	ErrOutOfStock       = newError("out_of_stock", "товара нет в наличии")
+	ErrVariantRequired  = newError("variant_required", "необходимо выбрать вариант товара")
+	ErrVariantNotFound  = newError("variant_not_found", "вариант товара не найден")
+	ErrVariantExists    = newError("variant_exists", "вариант товара уже существует")
+)
+
+// Заказы
+var (
+	ErrOrderNotFound       = newError("order_not_found", "заказ не найден")
+	ErrOrderNotCancellable = newError("order_not_cancellable", "заказ уже выдан или отменен")
+	ErrCancelWindowExpired = newError("cancel_window_expired", "срок отмены заказа истек")
+	ErrInvalidOrderStatus  = newError("invalid_order_status", "неизвестный статус заказа")
+	ErrInvalidTransition   = newError("invalid_status_transition", "недопустимый переход статуса заказа")
+)
+
+// Корзина
+var (
+	ErrCartEmpty        = newError("cart_empty", "корзина пуста")
+	ErrCartItemNotFound = newError("cart_item_not_found", "позиция корзины не найдена")
+	ErrCartChanged      = newError("cart_changed", "корзина изменилась во время оформления, повторите запрос")
+)
+
+// Ключи идемпотентности
+var (
+	ErrIdempotencyKeyReused     = newError("idempotency_key_reused", "ключ идемпотентности уже использован для другого запроса")
+	ErrIdempotencyKeyInProgress = newError("idempotency_key_in_progress", "запрос с этим ключом идемпотентности еще выполняется")
+	ErrIdempotencyKeyCommitted  = newError("idempotency_key_committed", "запрос с этим ключом идемпотентности уже выполнен, но его ответ не сохранен")
+)
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"merch-store/models"
+	"merch-store/repositories"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Размер страницы истории переводов
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// HistoryQuery - параметры запроса истории переводов.
+// Direction - "sent", "received" или пусто; From включительно, To не включительно; Cursor - из предыдущей страницы.
+type HistoryQuery struct {
+	Direction    string
+	Counterparty string
+	From         *time.Time
+	To           *time.Time
+	Cursor       string
+	Limit        int
+}
+
+// HistoryEntry - перевод в истории с направлением относительно пользователя
+type HistoryEntry struct {
+	models.Transaction
+	Direction string `json:"direction"`
+}
+
+// HistoryPage - страница истории, NextCursor пуст на последней странице
+type HistoryPage struct {
+	Transactions []HistoryEntry `json:"transactions"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+}
+
+// GetCoinHistory - постраничная история переводов пользователя, новые первыми
+func (s *CoinService) GetCoinHistory(username string, query HistoryQuery) (HistoryPage, error) {
+	if query.Direction != "" && query.Direction != "sent" && query.Direction != "received" {
+		return HistoryPage{}, ErrInvalidDirection
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultHistoryLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxHistoryLimit {
+		return HistoryPage{}, ErrInvalidLimit
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return HistoryPage{}, ErrInvalidDateRange
+	}
+
+	// created_at хранится без часового пояса в UTC
+	filter := repositories.TransactionFilter{
+		Username:     username,
+		Direction:    query.Direction,
+		Counterparty: query.Counterparty,
+		From:         utcTime(query.From),
+		To:           utcTime(query.To),
+		Limit:        query.Limit + 1,
+	}
+	if query.Cursor != "" {
+		createdAt, id, err := decodeHistoryCursor(query.Cursor)
+		if err != nil {
+			return HistoryPage{}, ErrInvalidCursor
+		}
+		filter.AfterCreatedAt = &createdAt
+		filter.AfterID = id
+	}
+
+	transactions, err := s.store.Transactions().ListTransactions(filter)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("error fetching transactions: %w", err)
+	}
+
+	// Лишняя запись означает, что есть следующая страница
+	page := HistoryPage{Transactions: make([]HistoryEntry, 0, len(transactions))}
+	if len(transactions) > query.Limit {
+		transactions = transactions[:query.Limit]
+		last := transactions[len(transactions)-1]
+		page.NextCursor = encodeHistoryCursor(last.CreatedAt, last.ID)
+	}
+	for _, t := range transactions {
+		direction := "sent"
+		if t.ToUser == username {
+			direction = "received"
+		}
+		page.Transactions = append(page.Transactions, HistoryEntry{Transaction: t, Direction: direction})
+	}
+
+	return page, nil
+}
+
+// utcTime - перевод времени фильтра в UTC, nil остается nil
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// encodeHistoryCursor - непрозрачный курсор из ключа последней записи страницы
+func encodeHistoryCursor(createdAt time.Time, id uint) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeHistoryCursor - разбор курсора, созданного encodeHistoryCursor
+func decodeHistoryCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("malformed cursor")
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	parsedID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.UnixMicro(usec).UTC(), uint(parsedID), nil
+}
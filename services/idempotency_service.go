@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"merch-store/models"
+	"merch-store/repositories"
+	"net/http"
+	"time"
+)
+
+// IdempotencyService - повторное выполнение запросов с ключом идемпотентности
+type IdempotencyService struct {
+	keys repositories.IdempotencyRepository
+	// reservationTimeout - время, после которого незавершенный запрос считается брошенным и ключ можно занять заново
+	reservationTimeout time.Duration
+	// ttl - время хранения ключей, после которого они удаляются очисткой
+	ttl time.Duration
+}
+
+// NewIdempotencyService - создание сервиса идемпотентности с таймаутом резервирования и временем хранения ключей
+func NewIdempotencyService(keys repositories.IdempotencyRepository, reservationTimeout, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{keys: keys, reservationTimeout: reservationTimeout, ttl: ttl}
+}
+
+// IdempotentRequest - запрос, за которым зарезервирован ключ идемпотентности.
+// Token отличает эту резервацию от резервации того же ключа повтором после таймаута.
+type IdempotentRequest struct {
+	Username string
+	Key      string
+	Token    string
+}
+
+// newIdempotencyToken - случайный токен резервации ключа
+func newIdempotencyToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// BeginIdempotentRequest - резервирование ключа перед выполнением запроса.
+// Возвращает резервацию для нового ключа или сохраненный результат, если запрос с таким ключом уже выполнялся.
+// Ключ, запрос по которому не завершился за reservationTimeout (например, процесс упал), резервируется заново,
+// если операция по нему не зафиксирована; иначе повтор получает ErrIdempotencyKeyCommitted.
+func (s *IdempotencyService) BeginIdempotentRequest(username, key, requestHash string) (*IdempotentRequest, *models.IdempotencyKey, error) {
+	token, err := newIdempotencyToken()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка создания токена идемпотентности: %w", err)
+	}
+	request := &IdempotentRequest{Username: username, Key: key, Token: token}
+
+	reserved, err := s.keys.ReserveIdempotencyKey(username, key, requestHash, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+	}
+	if reserved {
+		return request, nil, nil
+	}
+
+	record, err := s.keys.GetIdempotencyKey(username, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения ключа идемпотентности: %w", err)
+	}
+	if record.RequestHash != requestHash {
+		return nil, nil, ErrIdempotencyKeyReused
+	}
+	if record.StatusCode == nil {
+		if record.CommittedAt != nil {
+			return nil, nil, ErrIdempotencyKeyCommitted
+		}
+		reclaimed, err := s.keys.ReclaimIdempotencyKey(username, key, token, s.reservationTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+		}
+		if reclaimed {
+			return request, nil, nil
+		}
+		return nil, nil, ErrIdempotencyKeyInProgress
+	}
+
+	return nil, &record, nil
+}
+
+// CompleteIdempotentRequest - сохранение ответа для повторов.
+// Ответы с внутренней ошибкой не сохраняются, ключ освобождается для повторной попытки;
+// если ответ сохранить не удалось, ключ тоже освобождается. Ключ зафиксированной операции не освобождается.
+func (s *IdempotencyService) CompleteIdempotentRequest(request *IdempotentRequest, statusCode int, response []byte) error {
+	if statusCode >= http.StatusInternalServerError {
+		return s.keys.DeleteIdempotencyKey(request.Username, request.Key, request.Token)
+	}
+	if err := s.keys.SaveIdempotentResponse(request.Username, request.Key, request.Token, statusCode, response); err != nil {
+		if releaseErr := s.keys.DeleteIdempotencyKey(request.Username, request.Key, request.Token); releaseErr != nil {
+			return errors.Join(err, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotentRequest - освобождение ключа без сохранения ответа, например после паники обработчика
+func (s *IdempotencyService) ReleaseIdempotentRequest(request *IdempotentRequest) error {
+	return s.keys.DeleteIdempotencyKey(request.Username, request.Key, request.Token)
+}
+
+// CleanupIdempotencyKeys - удаление ключей старше ttl, возвращает число удаленных
+func (s *IdempotencyService) CleanupIdempotencyKeys() (int64, error) {
+	return s.keys.DeleteExpiredIdempotencyKeys(s.ttl)
+}
+
+// ScheduleCleanup - периодическая очистка устаревших ключей в фоне
+func (s *IdempotencyService) ScheduleCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := s.CleanupIdempotencyKeys()
+			if err != nil {
+				log.Println("Ошибка очистки ключей идемпотентности:", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Println("Удалено устаревших ключей идемпотентности:", deleted)
+			}
+		}
+	}()
+}
+
+// idempotentStore - хранилище, которое в транзакции бизнес-операции отмечает ключ идемпотентности зафиксированным.
+// Если ключ тем временем зарезервирован повтором с другим токеном или уже зафиксирован, транзакция откатывается,
+// поэтому операция по одному ключу фиксируется не больше одного раза.
+type idempotentStore struct {
+	repositories.Store
+	request *IdempotentRequest
+}
+
+// Atomic - выполнение fn и фиксация ключа идемпотентности в одной транзакции
+func (s idempotentStore) Atomic(fn func(tx repositories.Store) error) error {
+	return s.Store.Atomic(func(tx repositories.Store) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		committed, err := tx.IdempotencyKeys().CommitIdempotencyKey(s.request.Username, s.request.Key, s.request.Token)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения ключа идемпотентности: %w", err)
+		}
+		if !committed {
+			return ErrIdempotencyKeyInProgress
+		}
+		return nil
+	})
+}
@@ -2,56 +2,338 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"math"
 	"merch-store/models"
 	"merch-store/repositories"
 )
 
-// BuyItem - бизнес-логика для покупки товара
-func BuyItem(username, itemName string, amount int) error {
-	// Проверяем наличие товара
-	itemPrices := map[string]int{
-		"t-shirt": 80, "cup": 20, "book": 50, "pen": 10,
-		"powerbank": 200, "hoody": 300, "umbrella": 200,
-		"socks": 10, "wallet": 50, "pink-hoody": 500,
+// MaxOrderQuantity - максимальное количество товара в одной покупке или позиции корзины
+const MaxOrderQuantity = 1000
+
+// orderCost - стоимость quantity единиц товара по цене price.
+// Суммы хранятся в колонках INT, поэтому стоимость, не помещающаяся в int32, считается ошибкой количества.
+func orderCost(price, quantity int) (int, error) {
+	if quantity <= 0 || (price > 0 && quantity > math.MaxInt32/price) {
+		return 0, ErrInvalidQuantity
 	}
-	price, exists := itemPrices[itemName]
-	if !exists {
-		return errors.New("товар не найден")
+	return price * quantity, nil
+}
+
+// addCost - сумма стоимостей позиций с той же проверкой переполнения, что и в orderCost
+func addCost(total, cost int) (int, error) {
+	if total > math.MaxInt32-cost {
+		return 0, ErrInvalidQuantity
 	}
+	return total + cost, nil
+}
 
-	totalCost := price * amount
+// ItemService - каталог товаров и покупки
+type ItemService struct {
+	store repositories.Store
+}
+
+// NewItemService - создание сервиса каталога
+func NewItemService(store repositories.Store) *ItemService {
+	return &ItemService{store: store}
+}
+
+// BuyItem - бизнес-логика для покупки товара.
+// size и color выбирают вариант товара, для товаров без вариантов они должны быть пустыми.
+// Каждая покупка записывается в историю заказов.
+func (s *ItemService) BuyItem(username, itemName string, amount int, size, color string) (models.Order, error) {
+	if amount <= 0 || amount > MaxOrderQuantity {
+		return models.Order{}, ErrInvalidQuantity
+	}
+
+	// Проверяем наличие товара в каталоге
+	item, err := s.store.Items().GetActiveItem(itemName)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Order{}, ErrItemNotFound
+	}
+	if err != nil {
+		return models.Order{}, fmt.Errorf("ошибка получения данных товара: %w", err)
+	}
+
+	variant, err := selectVariant(s.store.Items(), item, size, color)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	totalCost, err := orderCost(item.Price, amount)
+	if err != nil {
+		return models.Order{}, err
+	}
 
 	// Проверяем баланс пользователя
-	var user models.User
-	err := repositories.DB.Get(&user, "SELECT id, coins FROM users WHERE name=$1", username)
+	user, err := findUser(s.store.Users(), username)
 	if err != nil {
-		return errors.New("ошибка получения данных пользователя")
+		return models.Order{}, err
 	}
 	if user.Coins < totalCost {
-		return errors.New("недостаточно монет")
+		return models.Order{}, ErrInsufficientFunds
 	}
 
 	// Обновляем баланс и добавляем товар в инвентарь
-	tx := repositories.DB.MustBegin()
+	var order models.Order
+	err = s.store.Atomic(func(tx repositories.Store) error {
+		// Повторно проверяем баланс при списании: между проверкой и транзакцией могли пройти другие операции
+		debited, err := tx.Users().DebitCoins(username, totalCost)
+		if err != nil {
+			return fmt.Errorf("ошибка обновления баланса: %w", err)
+		}
+		if !debited {
+			return ErrInsufficientFunds
+		}
+
+		order, err = issueItem(tx, user, item, variant, amount)
+		return err
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	return order, nil
+}
+
+// issueItem - выдача оплаченного товара в рамках транзакции покупки:
+// списание со склада, пополнение инвентаря пользователя, запись заказа и проводка оплаты в журнал
+func issueItem(tx repositories.Store, user models.User, item models.Item, variant *models.ItemVariant, amount int) (models.Order, error) {
+	total, err := orderCost(item.Price, amount)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	var inStock bool
+	if variant != nil {
+		inStock, err = tx.Items().DecrementVariantStock(variant.ID, amount)
+	} else {
+		inStock, err = tx.Items().DecrementStock(item.ID, amount)
+	}
+	if err != nil {
+		return models.Order{}, fmt.Errorf("ошибка обновления остатков: %w", err)
+	}
+	if !inStock {
+		return models.Order{}, ErrOutOfStock
+	}
+
+	variantLabel := ""
+	var variantID *uint
+	if variant != nil {
+		variantLabel = variant.Label()
+		variantID = &variant.ID
+	}
 
-	_, err = tx.Exec("UPDATE users SET coins = coins - $1 WHERE name = $2", totalCost, username)
+	err = tx.Inventory().AddInventory(user.ID, item.Name, variantLabel, amount)
 	if err != nil {
-		tx.Rollback()
-		return errors.New("ошибка обновления баланса")
+		return models.Order{}, fmt.Errorf("ошибка обновления инвентаря: %w", err)
 	}
 
-	_, err = tx.Exec(
-		"INSERT INTO inventory (user_id, item_name, amount) VALUES ($1, $2, $3) ON CONFLICT (user_id, item_name) DO UPDATE SET amount = inventory.amount + EXCLUDED.amount",
-		user.ID, itemName, amount)
+	order := models.Order{
+		UserID:    user.ID,
+		ItemID:    &item.ID,
+		ItemName:  item.Name,
+		VariantID: variantID,
+		Variant:   variantLabel,
+		Quantity:  amount,
+		UnitPrice: item.Price,
+		Total:     total,
+	}
+	err = tx.Orders().CreateOrder(&order)
 	if err != nil {
-		tx.Rollback()
-		return errors.New("ошибка обновления инвентаря")
+		return models.Order{}, fmt.Errorf("ошибка сохранения заказа: %w", err)
 	}
 
-	err = tx.Commit()
+	err = tx.Ledger().PostLedgerEntries(models.LedgerPurchase, models.OrderReference(order.ID),
+		models.Posting{Account: models.UserAccount(user.Username), Delta: -order.Total},
+		models.Posting{Account: models.AccountShop, Delta: order.Total})
+	if err != nil {
+		return models.Order{}, fmt.Errorf("ошибка записи в журнал проводок: %w", err)
+	}
+
+	return order, nil
+}
+
+// selectVariant - выбор варианта товара по размеру и цвету, nil для товара без вариантов
+func selectVariant(items repositories.ItemRepository, item models.Item, size, color string) (*models.ItemVariant, error) {
+	variants, err := items.GetItemVariants(item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных товара: %w", err)
+	}
+
+	if len(variants) == 0 {
+		if size != "" || color != "" {
+			return nil, ErrVariantNotFound
+		}
+		return nil, nil
+	}
+
+	if size == "" && color == "" {
+		return nil, ErrVariantRequired
+	}
+	for i := range variants {
+		if variants[i].Size == size && variants[i].Color == color {
+			return &variants[i], nil
+		}
+	}
+
+	return nil, ErrVariantNotFound
+}
+
+// itemSortOrders - допустимые варианты сортировки каталога
+var itemSortOrders = map[string]bool{"": true, "name": true, "-name": true, "price": true, "-price": true}
+
+// ListItems - список товаров каталога с фильтрацией по максимальной цене и сортировкой
+func (s *ItemService) ListItems(maxPrice int, sort string) ([]models.Item, error) {
+	if !itemSortOrders[sort] {
+		return nil, ErrInvalidItemSort
+	}
+
+	items, err := s.store.Items().ListActiveItems(maxPrice, sort)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения каталога: %w", err)
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	// Подгружаем варианты одним запросом для всех товаров
+	ids := make([]uint, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+	}
+	variants, err := s.store.Items().ListVariantsForItems(ids)
 	if err != nil {
-		return errors.New("ошибка сохранения транзакции")
+		return nil, fmt.Errorf("ошибка получения каталога: %w", err)
+	}
+
+	byItem := make(map[uint][]models.ItemVariant)
+	for _, v := range variants {
+		byItem[v.ItemID] = append(byItem[v.ItemID], v)
+	}
+	for i := range items {
+		items[i].Variants = byItem[items[i].ID]
+	}
+
+	return items, nil
+}
+
+// CreateItem - добавление товара в каталог администратором, stock == nil - без учета остатков
+func (s *ItemService) CreateItem(name string, price int, description string, stock *int) (models.Item, error) {
+	if price <= 0 {
+		return models.Item{}, ErrInvalidItemPrice
+	}
+	if stock != nil && *stock < 0 {
+		return models.Item{}, ErrInvalidQuantity
+	}
+
+	item := models.Item{Name: name, Price: price, Description: description, Active: true, Stock: stock}
+	err := s.store.Items().CreateItem(&item)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return models.Item{}, ErrItemExists
+	}
+	if err != nil {
+		return models.Item{}, fmt.Errorf("ошибка сохранения товара: %w", err)
+	}
+
+	return item, nil
+}
+
+// UpdateItem - изменение цены, описания или доступности товара администратором
+func (s *ItemService) UpdateItem(name string, price *int, description *string, active *bool) (models.Item, error) {
+	if price != nil && *price <= 0 {
+		return models.Item{}, ErrInvalidItemPrice
+	}
+
+	item, err := s.store.Items().UpdateItem(name, price, description, active)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Item{}, ErrItemNotFound
+	}
+	if err != nil {
+		return models.Item{}, fmt.Errorf("ошибка сохранения товара: %w", err)
+	}
+
+	return item, nil
+}
+
+// DeactivateItem - снятие товара с продажи администратором
+func (s *ItemService) DeactivateItem(name string) error {
+	found, err := s.store.Items().DeactivateItem(name)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения товара: %w", err)
+	}
+	if !found {
+		return ErrItemNotFound
 	}
 
 	return nil
 }
+
+// RestockItem - пополнение склада администратором, операция записывается в журнал
+func (s *ItemService) RestockItem(name string, quantity int, admin string) (models.Item, error) {
+	if quantity <= 0 {
+		return models.Item{}, ErrInvalidQuantity
+	}
+
+	item, err := s.store.Items().RestockItem(name, quantity, admin)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Item{}, ErrItemNotFound
+	}
+	if err != nil {
+		return models.Item{}, fmt.Errorf("ошибка пополнения склада: %w", err)
+	}
+
+	log.Printf("Склад пополнен: товар %s, количество %d, остаток %d, администратор %s", item.Name, quantity, *item.Stock, admin)
+
+	return item, nil
+}
+
+// CreateVariant - добавление варианта товара администратором
+func (s *ItemService) CreateVariant(itemName, size, color string, stock *int) (models.ItemVariant, error) {
+	if size == "" && color == "" {
+		return models.ItemVariant{}, ErrVariantRequired
+	}
+	if stock != nil && *stock < 0 {
+		return models.ItemVariant{}, ErrInvalidQuantity
+	}
+
+	item, err := s.store.Items().GetItem(itemName)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ItemVariant{}, ErrItemNotFound
+	}
+	if err != nil {
+		return models.ItemVariant{}, fmt.Errorf("ошибка получения данных товара: %w", err)
+	}
+
+	variant := models.ItemVariant{ItemID: item.ID, Size: size, Color: color, Stock: stock}
+	err = s.store.Items().CreateVariant(&variant)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return models.ItemVariant{}, ErrVariantExists
+	}
+	if err != nil {
+		return models.ItemVariant{}, fmt.Errorf("ошибка сохранения товара: %w", err)
+	}
+
+	return variant, nil
+}
+
+// RestockVariant - пополнение склада по варианту товара, операция записывается в журнал
+func (s *ItemService) RestockVariant(name, size, color string, quantity int, admin string) (models.ItemVariant, error) {
+	if quantity <= 0 {
+		return models.ItemVariant{}, ErrInvalidQuantity
+	}
+
+	variant, err := s.store.Items().RestockVariant(name, size, color, quantity, admin)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ItemVariant{}, ErrVariantNotFound
+	}
+	if err != nil {
+		return models.ItemVariant{}, fmt.Errorf("ошибка пополнения склада: %w", err)
+	}
+
+	log.Printf("Склад пополнен: товар %s (%s), количество %d, остаток %d, администратор %s",
+		name, variant.Label(), quantity, *variant.Stock, admin)
+
+	return variant, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"fmt"
+	"merch-store/repositories"
+)
+
+// LedgerReport - результат сверки журнала проводок с балансами пользователей
+type LedgerReport struct {
+	Consistent bool                               `json:"consistent"`
+	Mismatches []repositories.BalanceMismatch     `json:"mismatches"`
+	Unbalanced []repositories.UnbalancedReference `json:"unbalanced"`
+}
+
+// LedgerService - проверка журнала проводок
+type LedgerService struct {
+	ledger repositories.LedgerRepository
+}
+
+// NewLedgerService - создание сервиса журнала проводок
+func NewLedgerService(ledger repositories.LedgerRepository) *LedgerService {
+	return &LedgerService{ledger: ledger}
+}
+
+// CheckLedger - проверка, что каждая проводка сбалансирована,
+// а users.coins совпадает с суммой проводок по счету пользователя
+func (s *LedgerService) CheckLedger() (LedgerReport, error) {
+	mismatches, err := s.ledger.ListBalanceMismatches()
+	if err != nil {
+		return LedgerReport{}, fmt.Errorf("error checking balances: %w", err)
+	}
+
+	unbalanced, err := s.ledger.ListUnbalancedReferences()
+	if err != nil {
+		return LedgerReport{}, fmt.Errorf("error checking ledger postings: %w", err)
+	}
+
+	return LedgerReport{
+		Consistent: len(mismatches) == 0 && len(unbalanced) == 0,
+		Mismatches: mismatches,
+		Unbalanced: unbalanced,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"merch-store/models"
+	"merch-store/repositories"
+	"time"
+)
+
+// orderTransitions - допустимые переходы статусов заказа при выдаче
+var orderTransitions = map[string][]string{
+	models.OrderPlaced:         {models.OrderPacked, models.OrderCancelled},
+	models.OrderPacked:         {models.OrderReadyForPickup, models.OrderCancelled},
+	models.OrderReadyForPickup: {models.OrderDelivered, models.OrderCancelled},
+}
+
+// isKnownOrderStatus - проверка, что статус входит в жизненный цикл заказа
+func isKnownOrderStatus(status string) bool {
+	switch status {
+	case models.OrderPlaced, models.OrderPacked, models.OrderReadyForPickup, models.OrderDelivered, models.OrderCancelled:
+		return true
+	}
+	return false
+}
+
+// canTransition - проверка перехода заказа из статуса from в статус to
+func canTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderService - история заказов, отмена и выдача
+type OrderService struct {
+	store repositories.Store
+	// cancelWindow - время после покупки, в течение которого заказ можно отменить
+	cancelWindow time.Duration
+}
+
+// NewOrderService - создание сервиса заказов с окном отмены cancelWindow
+func NewOrderService(store repositories.Store, cancelWindow time.Duration) *OrderService {
+	return &OrderService{store: store, cancelWindow: cancelWindow}
+}
+
+// ListOrders - история заказов пользователя
+func (s *OrderService) ListOrders(username string) ([]models.Order, error) {
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.store.Orders().ListUserOrders(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// CancelOrder - отмена заказа пользователем с возвратом монет.
+// Возврат монет, списание из инвентаря и запись о возврате выполняются в одной транзакции.
+func (s *OrderService) CancelOrder(username string, orderID uint) (models.Refund, error) {
+	user, err := findUser(s.store.Users(), username)
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	var refund models.Refund
+	err = s.store.Atomic(func(tx repositories.Store) error {
+		order, err := tx.Orders().GetOrderForUpdate(orderID, user.ID, s.cancelWindow)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка получения данных заказа: %w", err)
+		}
+		if order.Status != models.OrderPlaced {
+			return ErrOrderNotCancellable
+		}
+		if order.Expired {
+			return ErrCancelWindowExpired
+		}
+
+		refund, err = tx.Orders().CancelOrder(order.Order)
+		if err != nil {
+			return fmt.Errorf("ошибка отмены заказа: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Refund{}, err
+	}
+
+	return refund, nil
+}
+
+// ListOrdersByStatus - заказы для выдачи, отфильтрованные по статусу
+func (s *OrderService) ListOrdersByStatus(status string) ([]models.Order, error) {
+	if status != "" && !isKnownOrderStatus(status) {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	orders, err := s.store.Orders().ListOrdersByStatus(status)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// UpdateOrderStatus - перевод заказа в следующий статус администратором.
+// Отмена заказа на любом этапе до выдачи возвращает монеты так же, как отмена пользователем.
+func (s *OrderService) UpdateOrderStatus(orderID uint, status string) (models.Order, error) {
+	if !isKnownOrderStatus(status) {
+		return models.Order{}, ErrInvalidOrderStatus
+	}
+
+	var order models.Order
+	err := s.store.Atomic(func(tx repositories.Store) error {
+		var err error
+		order, err = tx.Orders().GetOrderByIDForUpdate(orderID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка получения данных заказа: %w", err)
+		}
+		if !canTransition(order.Status, status) {
+			return ErrInvalidTransition
+		}
+
+		if status == models.OrderCancelled {
+			_, err = tx.Orders().CancelOrder(order)
+		} else {
+			err = tx.Orders().UpdateOrderStatus(order.ID, status)
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка обновления заказа: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	order.Status = status
+	return order, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"merch-store/repositories"
+	"time"
+)
+
+// ReconciliationReport - результат сверки балансов с историей операций
+type ReconciliationReport struct {
+	CheckedAt  time.Time                      `json:"checkedAt"`
+	Mismatches []repositories.BalanceMismatch `json:"mismatches"`
+	Adjusted   bool                           `json:"adjusted"`
+}
+
+// ReconciliationService - сверка балансов с журналом проводок
+type ReconciliationService struct {
+	store repositories.Store
+}
+
+// NewReconciliationService - создание сервиса сверки
+func NewReconciliationService(store repositories.Store) *ReconciliationService {
+	return &ReconciliationService{store: store}
+}
+
+// Reconcile - сверка баланса каждого пользователя с суммой проводок по его счету.
+// Журнал проводок - источник истины, поэтому при adjust users.coins приводится к балансу по журналу,
+// а изменение записывается в balance_adjustments; журнал при этом не меняется.
+// Расхождения ищутся и исправляются в одной транзакции под блокировкой сверки,
+// поэтому одновременные запуски не применяют корректировку дважды. Баланс меняется на разницу,
+// а не перезаписывается, так что операции, зафиксированные во время сверки, не теряются.
+func (s *ReconciliationService) Reconcile(adjust bool) (ReconciliationReport, error) {
+	report := ReconciliationReport{CheckedAt: time.Now().UTC()}
+	note := "reconciliation " + report.CheckedAt.Format(time.RFC3339)
+
+	err := s.store.Atomic(func(tx repositories.Store) error {
+		if err := tx.Ledger().LockReconciliation(); err != nil {
+			return fmt.Errorf("error locking reconciliation: %w", err)
+		}
+
+		mismatches, err := tx.Ledger().ListBalanceMismatches()
+		if err != nil {
+			return fmt.Errorf("error computing balances: %w", err)
+		}
+		report.Mismatches = mismatches
+
+		if !adjust {
+			return nil
+		}
+		for _, m := range mismatches {
+			if _, err := tx.Ledger().CreateBalanceAdjustment(m.UserID, -m.Difference, note); err != nil {
+				return fmt.Errorf("adjust %s: %w", m.Username, err)
+			}
+			if err := correctCoins(tx.Users(), m); err != nil {
+				return fmt.Errorf("adjust %s: %w", m.Username, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	report.Adjusted = adjust && len(report.Mismatches) > 0
+
+	return report, nil
+}
+
+// correctCoins - приведение users.coins к балансу по журналу
+func correctCoins(users repositories.UserRepository, m repositories.BalanceMismatch) error {
+	var ok bool
+	var err error
+	if m.Difference > 0 {
+		ok, err = users.DebitCoins(m.Username, m.Difference)
+	} else {
+		ok, err = users.CreditCoins(m.Username, -m.Difference)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("balance cannot be corrected to ledger balance %d", m.Ledger)
+	}
+	return nil
+}
+
+// ScheduleReconciliation - периодическая сверка балансов в фоне, расхождения пишутся в лог в JSON
+func (s *ReconciliationService) ScheduleReconciliation(interval time.Duration, adjust bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, err := s.Reconcile(adjust)
+			if err != nil {
+				log.Println("Ошибка сверки балансов:", err)
+				continue
+			}
+			if len(report.Mismatches) == 0 {
+				continue
+			}
+			data, _ := json.Marshal(report)
+			log.Printf("Сверка балансов: найдены расхождения: %s", data)
+		}
+	}()
+}
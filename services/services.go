@@ -0,0 +1,60 @@
+package services
+
+import (
+	"merch-store/repositories"
+	"time"
+)
+
+// Options - настройки бизнес-логики магазина
+type Options struct {
+	// StartingCoins - монеты, начисляемые при регистрации
+	StartingCoins int
+	// CancelWindow - время после покупки, в течение которого заказ можно отменить
+	CancelWindow time.Duration
+	// IdempotencyTimeout - время, после которого незавершенный запрос с ключом идемпотентности считается брошенным
+	IdempotencyTimeout time.Duration
+	// IdempotencyTTL - время хранения ключей идемпотентности
+	IdempotencyTTL time.Duration
+}
+
+// DefaultOptions - настройки по умолчанию
+func DefaultOptions() Options {
+	return Options{StartingCoins: 1000, CancelWindow: 24 * time.Hour, IdempotencyTimeout: time.Minute, IdempotencyTTL: 24 * time.Hour}
+}
+
+// Services - сервисы магазина, работающие с одним хранилищем
+type Services struct {
+	Users          *UserService
+	Coins          *CoinService
+	Items          *ItemService
+	Orders         *OrderService
+	Carts          *CartService
+	Idempotency    *IdempotencyService
+	Ledger         *LedgerService
+	Reconciliation *ReconciliationService
+
+	store repositories.Store
+	opts  Options
+}
+
+// New - создание всех сервисов поверх хранилища
+func New(store repositories.Store, opts Options) *Services {
+	return &Services{
+		Users:          NewUserService(store, opts.StartingCoins),
+		Coins:          NewCoinService(store),
+		Items:          NewItemService(store),
+		Orders:         NewOrderService(store, opts.CancelWindow),
+		Carts:          NewCartService(store),
+		Idempotency:    NewIdempotencyService(store.IdempotencyKeys(), opts.IdempotencyTimeout, opts.IdempotencyTTL),
+		Ledger:         NewLedgerService(store.Ledger()),
+		Reconciliation: NewReconciliationService(store),
+		store:          store,
+		opts:           opts,
+	}
+}
+
+// WithIdempotentRequest - сервисы, бизнес-операции которых фиксируют ключ идемпотентности request
+// в своей транзакции, поэтому повтор после сбоя не выполнит операцию второй раз
+func (s *Services) WithIdempotentRequest(request *IdempotentRequest) *Services {
+	return New(idempotentStore{Store: s.store, request: request}, s.opts)
+}
@@ -1,99 +1,545 @@
 package services
 
 import (
-	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/jmoiron/sqlx"
+	"database/sql"
 	"github.com/stretchr/testify/assert"
+	"math"
+	"merch-store/models"
 	"merch-store/repositories"
-	"regexp"
+	"merch-store/utils"
 	"testing"
+	"time"
 )
 
-func setupMockDB() (*sqlx.DB, sqlmock.Sqlmock) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		panic(err)
+// setupServices - сервисы поверх пустого хранилища в памяти
+func setupServices() (*Services, *repositories.MemoryStore) {
+	store := repositories.NewMemoryStore()
+	return New(store, DefaultOptions()), store
+}
+
+// seedCatalog - тестовый каталог: ручек на складе 1, худи продается только в вариантах
+func seedCatalog(t *testing.T, svc *Services, store *repositories.MemoryStore) {
+	penStock := 1
+	err := store.SeedItems([]models.Item{
+		{Name: "t-shirt", Price: 80, Active: true},
+		{Name: "cup", Price: 20, Active: true},
+		{Name: "book", Price: 50, Active: true},
+		{Name: "pen", Price: 10, Active: true, Stock: &penStock},
+		{Name: "hoody", Price: 300, Active: true},
+	})
+	assert.NoError(t, err)
+
+	for _, size := range []string{"M", "L"} {
+		stock := 3
+		_, err = svc.Items.CreateVariant("hoody", size, "black", &stock)
+		assert.NoError(t, err)
 	}
-	sqlxDB := sqlx.NewDb(db, "sqlmock")
-	return sqlxDB, mock
 }
 
-func TestSendCoinService(t *testing.T) {
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+// registerUsers - регистрация пользователей с начальным балансом
+func registerUsers(t *testing.T, svc *Services, names ...string) {
+	for _, name := range names {
+		assert.NoError(t, svc.Users.RegisterUser(name, "password123"))
+	}
+}
+
+// coins - текущий баланс пользователя
+func coins(t *testing.T, store repositories.Store, username string) int {
+	user, err := store.Users().GetUserByName(username)
+	assert.NoError(t, err)
+	return user.Coins
+}
+
+// unavailableCatalogStore - хранилище, в котором каталог товаров недоступен
+type unavailableCatalogStore struct {
+	repositories.Store
+}
+
+func (s unavailableCatalogStore) Items() repositories.ItemRepository {
+	return unavailableCatalog{s.Store.Items()}
+}
+
+type unavailableCatalog struct {
+	repositories.ItemRepository
+}
+
+func (unavailableCatalog) GetActiveItem(name string) (models.Item, error) {
+	return models.Item{}, sql.ErrConnDone
+}
+
+// checkoutRaceStore - хранилище, имитирующее изменение корзины параллельным запросом во время оформления
+type checkoutRaceStore struct {
+	repositories.Store
+	// added - последняя позиция добавлена после чтения корзины и в оформление не попадает
+	added bool
+	// removed - прочитанные позиции к моменту удаления уже удалены другим запросом
+	removed bool
+}
+
+func (s checkoutRaceStore) Carts() repositories.CartRepository {
+	return checkoutRaceCart{s.Store.Carts(), s}
+}
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"coins"}).AddRow(1000))
+func (s checkoutRaceStore) Atomic(fn func(tx repositories.Store) error) error {
+	return s.Store.Atomic(func(tx repositories.Store) error {
+		return fn(checkoutRaceStore{tx, s.added, s.removed})
+	})
+}
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM users WHERE name=$1")).
-		WithArgs("user2").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+type checkoutRaceCart struct {
+	repositories.CartRepository
+	store checkoutRaceStore
+}
 
-	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins - $1 WHERE name = $2")).
-		WithArgs(100, "user1").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+func (c checkoutRaceCart) ListCartForUpdate(userID uint) ([]models.CartItem, error) {
+	lines, err := c.CartRepository.ListCartForUpdate(userID)
+	if c.store.added && len(lines) > 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, err
+}
 
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins + $1 WHERE name = $2")).
-		WithArgs(100, "user2").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+func (c checkoutRaceCart) RemoveCartItems(userID uint, cartItemIDs []uint) (int64, error) {
+	if c.store.removed {
+		return 0, nil
+	}
+	return c.CartRepository.RemoveCartItems(userID, cartItemIDs)
+}
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO transactions")).
-		WithArgs("user1", "user2", 100).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+func TestSendCoinService(t *testing.T) {
+	svc, store := setupServices()
+	registerUsers(t, svc, "user1", "user2")
 
-	mock.ExpectCommit()
+	transaction, err := svc.Coins.SendCoin("user1", "user2", 100, "")
+	assert.NoError(t, err)
+	assert.NotZero(t, transaction.ID)
+	assert.Equal(t, 900, coins(t, store, "user1"))
+	assert.Equal(t, 1100, coins(t, store, "user2"))
 
-	err := SendCoin("user1", "user2", 100)
+	report, err := svc.Ledger.CheckLedger()
 	assert.NoError(t, err)
-	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, report.Consistent)
 }
 
 func TestBuyItemService(t *testing.T) {
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "coins"}).AddRow(1, 1000))
+	order, err := svc.Items.BuyItem("user1", "t-shirt", 2, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 160, order.Total)
+	assert.Equal(t, models.OrderPlaced, order.Status)
+	assert.Equal(t, 840, coins(t, store, "user1"))
 
-	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET coins = coins - $1 WHERE name = $2")).
-		WithArgs(160, "user1").
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	info, err := svc.Users.GetUserInfo("user1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []UserItem{{ItemName: "t-shirt", Amount: 2}}, info.Inventory)
+}
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO inventory")).
-		WithArgs(1, "t-shirt", 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+func TestGetUserInfoService(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1", "user2")
 
-	mock.ExpectCommit()
+	transaction, err := svc.Coins.SendCoin("user2", "user1", 100, "thanks for the release help!")
+	assert.NoError(t, err)
+	_, err = svc.Items.BuyItem("user1", "t-shirt", 2, "", "")
+	assert.NoError(t, err)
+	order, err := svc.Items.BuyItem("user1", "hoody", 1, "L", "black")
+	assert.NoError(t, err)
+	_, err = svc.Orders.UpdateOrderStatus(order.ID, models.OrderPacked)
+	assert.NoError(t, err)
 
-	err := BuyItem("user1", "t-shirt", 2)
+	userInfo, err := svc.Users.GetUserInfo("user1", false)
 	assert.NoError(t, err)
-	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1100-160-300, userInfo.Coins)
+	assert.Equal(t, 2, len(userInfo.Inventory))
+	assert.Equal(t, "hoody (L, black)", userInfo.Inventory[1].ItemName)
+	assert.Equal(t, 1, len(userInfo.CoinHistory["received"]))
+	assert.Equal(t, "thanks for the release help!", userInfo.CoinHistory["received"][0]["message"])
+	assert.Equal(t, transaction.ID, userInfo.CoinHistory["received"][0]["id"])
+	assert.Equal(t, models.OrderPacked, userInfo.Orders[0].Status)
 }
 
-func TestGetUserInfoService(t *testing.T) {
-	sqlxDB, mock := setupMockDB()
-	repositories.DB = sqlxDB
+func TestBuyItemServiceItemNotFound(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+
+	_, err := svc.Items.BuyItem("user1", "sword", 1, "", "")
+	assert.EqualError(t, err, "товар не найден")
+}
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, coins FROM users WHERE name=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "coins"}).AddRow(1, "user1", 1000))
+func TestBuyItemServiceKeepsCause(t *testing.T) {
+	store := repositories.NewMemoryStore()
+	registerUsers(t, New(store, DefaultOptions()), "user1")
+	svc := New(unavailableCatalogStore{store}, DefaultOptions())
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT item_name, amount FROM inventory WHERE user_id=$1")).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"item_name", "amount"}).AddRow("t-shirt", 2))
+	// Ошибка хранилища не теряется за сообщением сервиса
+	_, err := svc.Items.BuyItem("user1", "cup", 1, "", "")
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	_, err = svc.Carts.AddToCart("user1", "cup", 1, "", "")
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestBuyItemServiceQuantityOverflow(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+	_, err := svc.Items.CreateItem("yacht", math.MaxInt32/2, "", nil)
+	assert.NoError(t, err)
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT from_user, to_user, amount FROM transactions WHERE from_user=$1 OR to_user=$1")).
-		WithArgs("user1").
-		WillReturnRows(sqlmock.NewRows([]string{"from_user", "to_user", "amount"}).AddRow("user2", "user1", 100))
+	_, err = svc.Items.BuyItem("user1", "pen", 1844674407370955161, "", "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	_, err = svc.Items.BuyItem("user1", "pen", MaxOrderQuantity+1, "", "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
 
-	userInfo, err := GetUserInfo("user1")
+	// Стоимость не помещается в INT, даже если количество допустимо
+	_, err = svc.Items.BuyItem("user1", "yacht", 3, "", "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	// Позиция корзины не может превысить предел за счет повторных добавлений
+	_, err = svc.Carts.AddToCart("user1", "cup", MaxOrderQuantity, "", "")
 	assert.NoError(t, err)
-	assert.Equal(t, 1000, userInfo.Coins)
-	assert.Equal(t, 1, len(userInfo.Inventory))
-	assert.Equal(t, 1, len(userInfo.CoinHistory["received"]))
-	assert.NoError(t, mock.ExpectationsWereMet())
+	_, err = svc.Carts.AddToCart("user1", "cup", 1, "", "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	cart, err := svc.Carts.GetCart("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, MaxOrderQuantity, cart.Items[0].Quantity)
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+}
+
+func TestListItemsService(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	stock := 5
+	_, err := svc.Items.CreateVariant("cup", "", "white", &stock)
+	assert.NoError(t, err)
+
+	items, err := svc.Items.ListItems(50, "-price")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, "book", items[0].Name)
+	assert.Empty(t, items[0].Variants)
+	assert.Equal(t, "cup", items[1].Name)
+	assert.Equal(t, "white", items[1].Variants[0].Color)
+
+	_, err = svc.Items.ListItems(0, "color")
+	assert.ErrorIs(t, err, ErrInvalidItemSort)
+}
+
+func TestRestockItemService(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+
+	item, err := svc.Items.RestockItem("pen", 25, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, 26, *item.Stock)
+
+	_, err = svc.Items.RestockItem("pen", 0, "admin")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+}
+
+func TestBuyItemServiceVariant(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+
+	// Без выбора варианта покупка невозможна
+	_, err := svc.Items.BuyItem("user1", "hoody", 1, "", "")
+	assert.ErrorIs(t, err, ErrVariantRequired)
+
+	order, err := svc.Items.BuyItem("user1", "hoody", 1, "L", "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "L, black", order.Variant)
+	assert.Equal(t, 700, coins(t, store, "user1"))
+
+	hoody, err := store.Items().GetItem("hoody")
+	assert.NoError(t, err)
+	variants, err := store.Items().GetItemVariants(hoody.ID)
+	assert.NoError(t, err)
+	for _, variant := range variants {
+		if variant.Size == "L" {
+			assert.Equal(t, 2, *variant.Stock)
+		} else {
+			assert.Equal(t, 3, *variant.Stock)
+		}
+	}
+}
+
+func TestCancelOrderService(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+
+	order, err := svc.Items.BuyItem("user1", "cup", 3, "", "")
+	assert.NoError(t, err)
+
+	refund, err := svc.Orders.CancelOrder("user1", order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 60, refund.Amount)
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+
+	// Выданный заказ отменить нельзя
+	order, err = svc.Items.BuyItem("user1", "cup", 1, "", "")
+	assert.NoError(t, err)
+	for _, status := range []string{models.OrderPacked, models.OrderReadyForPickup, models.OrderDelivered} {
+		_, err = svc.Orders.UpdateOrderStatus(order.ID, status)
+		assert.NoError(t, err)
+	}
+
+	_, err = svc.Orders.CancelOrder("user1", order.ID)
+	assert.ErrorIs(t, err, ErrOrderNotCancellable)
+	assert.Equal(t, 980, coins(t, store, "user1"))
+}
+
+func TestCheckoutService(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+
+	_, err := svc.Carts.AddToCart("user1", "cup", 2, "", "")
+	assert.NoError(t, err)
+	_, err = svc.Carts.AddToCart("user1", "pen", 3, "", "")
+	assert.NoError(t, err)
+
+	// Ручек на складе меньше, чем в корзине: вся покупка откатывается
+	_, err = svc.Carts.Checkout("user1")
+	assert.ErrorIs(t, err, ErrOutOfStock)
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+	orders, err := svc.Orders.ListOrders("user1")
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+
+	_, err = svc.Items.RestockItem("pen", 2, "admin")
+	assert.NoError(t, err)
+
+	orders, err = svc.Carts.Checkout("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(orders))
+	assert.Equal(t, 930, coins(t, store, "user1"))
+
+	cart, err := svc.Carts.GetCart("user1")
+	assert.NoError(t, err)
+	assert.Empty(t, cart.Items)
+}
+
+func TestCheckoutServiceCartChanged(t *testing.T) {
+	svc, store := setupServices()
+	seedCatalog(t, svc, store)
+	registerUsers(t, svc, "user1")
+
+	_, err := svc.Carts.AddToCart("user1", "cup", 2, "", "")
+	assert.NoError(t, err)
+	_, err = svc.Carts.AddToCart("user1", "t-shirt", 1, "", "")
+	assert.NoError(t, err)
+
+	// Позиции удалены другим запросом: оформление откатывается целиком
+	racing := New(checkoutRaceStore{Store: store, removed: true}, DefaultOptions())
+	_, err = racing.Carts.Checkout("user1")
+	assert.ErrorIs(t, err, ErrCartChanged)
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+
+	// Позиция, добавленная после чтения корзины, не оплачивается и остается в корзине
+	racing = New(checkoutRaceStore{Store: store, added: true}, DefaultOptions())
+	orders, err := racing.Carts.Checkout("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(orders))
+	assert.Equal(t, 960, coins(t, store, "user1"))
+
+	cart, err := svc.Carts.GetCart("user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(cart.Items))
+	assert.Equal(t, "t-shirt", cart.Items[0].ItemName)
+}
+
+func TestBeginIdempotentRequestService(t *testing.T) {
+	svc, _ := setupServices()
+
+	// Новый ключ резервируется, запрос выполняется
+	request, saved, err := svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-1")
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+	assert.NoError(t, svc.Idempotency.CompleteIdempotentRequest(request, 200, []byte(`{"orderId":1}`)))
+
+	// Повтор того же запроса возвращает сохраненный ответ
+	request, saved, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-1")
+	assert.NoError(t, err)
+	assert.Nil(t, request)
+	assert.Equal(t, 200, *saved.StatusCode)
+	assert.Equal(t, `{"orderId":1}`, string(saved.Response))
+
+	// Тот же ключ с другим телом запроса
+	_, _, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-2")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// Запрос еще выполняется: ключ зарезервирован недавно и не может быть занят заново
+	request, saved, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-2", "hash-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, request)
+	assert.Nil(t, saved)
+
+	_, _, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-2", "hash-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+}
+
+func TestIdempotentRequestCommitsKeyWithOperation(t *testing.T) {
+	svc, store := setupServices()
+	registerUsers(t, svc, "user1", "user2")
+
+	request, _, err := svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-1")
+	assert.NoError(t, err)
+
+	// Операция, которая не прошла, ключ не фиксирует
+	_, err = svc.WithIdempotentRequest(request).Coins.SendCoin("user1", "user2", 5000, "")
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	record, err := store.IdempotencyKeys().GetIdempotencyKey("user1", "key-1")
+	assert.NoError(t, err)
+	assert.Nil(t, record.CommittedAt)
+
+	// Успешная операция фиксирует ключ в своей транзакции, до сохранения ответа
+	_, err = svc.WithIdempotentRequest(request).Coins.SendCoin("user1", "user2", 100, "")
+	assert.NoError(t, err)
+	record, err = store.IdempotencyKeys().GetIdempotencyKey("user1", "key-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, record.CommittedAt)
+	assert.Nil(t, record.StatusCode)
+
+	_, _, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyCommitted)
+
+	// Повторное выполнение с той же резервацией откатывается
+	_, err = svc.WithIdempotentRequest(request).Coins.SendCoin("user1", "user2", 100, "")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	assert.Equal(t, 900, coins(t, store, "user1"))
+
+	// Освобождение не снимает фиксацию
+	assert.NoError(t, svc.Idempotency.ReleaseIdempotentRequest(request))
+	_, _, err = svc.Idempotency.BeginIdempotentRequest("user1", "key-1", "hash-1")
+	assert.ErrorIs(t, err, ErrIdempotencyKeyCommitted)
+}
+
+func TestSendCoinServiceInsufficientFunds(t *testing.T) {
+	svc, store := setupServices()
+	registerUsers(t, svc, "user1", "user2")
+
+	_, err := svc.Coins.SendCoin("user1", "user2", 5000, "")
+	assert.EqualError(t, err, "недостаточно монет")
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+	assert.Equal(t, 1000, coins(t, store, "user2"))
+}
+
+func TestErrorMessagesLocalized(t *testing.T) {
+	for _, err := range knownErrors {
+		for _, lang := range []string{"ru", "en"} {
+			assert.NotEmpty(t, utils.Message(lang, err.Code, ""), "нет сообщения %s для %s", lang, err.Code)
+		}
+	}
+}
+
+func TestGetCoinHistoryService(t *testing.T) {
+	svc, _ := setupServices()
+	registerUsers(t, svc, "user1", "user2", "user3")
+
+	for _, amount := range []int{30, 20, 10} {
+		_, err := svc.Coins.SendCoin("user2", "user1", amount, "")
+		assert.NoError(t, err)
+	}
+	_, err := svc.Coins.SendCoin("user3", "user1", 40, "")
+	assert.NoError(t, err)
+
+	// Первая страница: новые переводы первыми, есть курсор на следующую
+	page, err := svc.Coins.GetCoinHistory("user1", HistoryQuery{Direction: "received", Counterparty: "user2", Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(page.Transactions))
+	assert.Equal(t, "received", page.Transactions[0].Direction)
+	assert.Equal(t, 10, page.Transactions[0].Amount)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// Следующая страница продолжается после последней записи
+	page, err = svc.Coins.GetCoinHistory("user1", HistoryQuery{Direction: "received", Counterparty: "user2", Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page.Transactions))
+	assert.Equal(t, 30, page.Transactions[0].Amount)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetCoinHistoryServiceInvalidQuery(t *testing.T) {
+	svc, _ := setupServices()
+
+	from := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+
+	_, err := svc.Coins.GetCoinHistory("user1", HistoryQuery{Direction: "all"})
+	assert.ErrorIs(t, err, ErrInvalidDirection)
+	_, err = svc.Coins.GetCoinHistory("user1", HistoryQuery{Limit: MaxHistoryLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+	_, err = svc.Coins.GetCoinHistory("user1", HistoryQuery{From: &from, To: &to})
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+	_, err = svc.Coins.GetCoinHistory("user1", HistoryQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestGetUserInfoServiceGroupedHistory(t *testing.T) {
+	svc, _ := setupServices()
+	registerUsers(t, svc, "user1", "user2", "user3")
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Coins.SendCoin("user2", "user1", 100, "")
+		assert.NoError(t, err)
+	}
+	_, err := svc.Coins.SendCoin("user1", "user3", 50, "")
+	assert.NoError(t, err)
+
+	userInfo, err := svc.Users.GetUserInfo("user1", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"fromUser": "user2", "amount": 300, "count": 3}}, userInfo.CoinHistory["received"])
+	assert.Equal(t, []map[string]interface{}{{"toUser": "user3", "amount": 50, "count": 1}}, userInfo.CoinHistory["sent"])
+}
+
+func TestCheckLedgerService(t *testing.T) {
+	svc, store := setupServices()
+	registerUsers(t, svc, "user1")
+
+	// Баланс изменен в обход журнала проводок
+	debited, err := store.Users().DebitCoins("user1", 100)
+	assert.NoError(t, err)
+	assert.True(t, debited)
+
+	report, err := svc.Ledger.CheckLedger()
+	assert.NoError(t, err)
+	assert.False(t, report.Consistent)
+	assert.Equal(t, []repositories.BalanceMismatch{{UserID: 1, Username: "user1", Coins: 900, Ledger: 1000, Difference: -100}}, report.Mismatches)
+	assert.Empty(t, report.Unbalanced)
+}
+
+func TestReconcileService(t *testing.T) {
+	svc, store := setupServices()
+	registerUsers(t, svc, "user1", "user2")
+
+	debited, err := store.Users().DebitCoins("user2", 100)
+	assert.NoError(t, err)
+	assert.True(t, debited)
+
+	// Без -adjust только отчет
+	report, err := svc.Reconciliation.Reconcile(false)
+	assert.NoError(t, err)
+	assert.False(t, report.Adjusted)
+	assert.Equal(t, 1, len(report.Mismatches))
+	assert.Equal(t, -100, report.Mismatches[0].Difference)
+
+	assert.Equal(t, 900, coins(t, store, "user2"))
+
+	// С корректировками баланс приводится к журналу
+	report, err = svc.Reconciliation.Reconcile(true)
+	assert.NoError(t, err)
+	assert.True(t, report.Adjusted)
+	assert.Equal(t, 1000, coins(t, store, "user2"))
+	assert.Equal(t, 1000, coins(t, store, "user1"))
+
+	ledger, err := svc.Ledger.CheckLedger()
+	assert.NoError(t, err)
+	assert.True(t, ledger.Consistent)
 }
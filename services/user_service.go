@@ -8,40 +8,80 @@ import (
 	"merch-store/utils"
 )
 
-// RegisterUser - регистрация нового пользователя
-func RegisterUser(username, password string) error {
-	// Хешируем пароль
-	hash, err := utils.HashPassword(password)
+// findUser - поиск пользователя по имени, ErrUserNotFound если его нет
+func findUser(users repositories.UserRepository, username string) (models.User, error) {
+	user, err := users.GetUserByName(username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.User{}, ErrUserNotFound
+	}
 	if err != nil {
-		return errors.New("внутренняя ошибка сервера")
+		return models.User{}, fmt.Errorf("error fetching user: %w", err)
 	}
+	return user, nil
+}
+
+// UserService - регистрация, аутентификация и профиль пользователя
+type UserService struct {
+	store         repositories.Store
+	startingCoins int
+}
+
+// NewUserService - создание сервиса пользователей, startingCoins начисляются при регистрации
+func NewUserService(store repositories.Store, startingCoins int) *UserService {
+	return &UserService{store: store, startingCoins: startingCoins}
+}
 
-	// Создаем пользователя в базе данных
-	_, err = repositories.DB.Exec("INSERT INTO users (name, password, coins) VALUES ($1, $2, $3)", username, hash, 1000)
+// RegisterUser - регистрация нового пользователя, стартовые монеты проводятся через журнал
+func (s *UserService) RegisterUser(username, password string) error {
+	// Хешируем пароль
+	hash, err := utils.HashPassword(password)
 	if err != nil {
-		return errors.New("пользователь уже существует")
+		return fmt.Errorf("внутренняя ошибка сервера: %w", err)
 	}
 
-	return nil
+	return s.store.Atomic(func(tx repositories.Store) error {
+		// Создаем пользователя в базе данных
+		user := models.User{Username: username, Password: hash, Coins: s.startingCoins}
+		err := tx.Users().CreateUser(&user)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			return ErrUserExists
+		}
+		if err != nil {
+			return fmt.Errorf("error creating user: %w", err)
+		}
+
+		if s.startingCoins > 0 {
+			err = tx.Ledger().PostLedgerEntries(models.LedgerGrant, "registration:"+username,
+				models.Posting{Account: models.AccountGrants, Delta: -s.startingCoins},
+				models.Posting{Account: models.UserAccount(username), Delta: s.startingCoins})
+			if err != nil {
+				return fmt.Errorf("post starting grant: %w", err)
+			}
+		}
+
+		return nil
+	})
 }
 
 // AuthenticateUser - аутентификация пользователя
-func AuthenticateUser(username, password string) (string, error) {
-	var user models.User
-	err := repositories.DB.Get(&user, "SELECT id, name, password, coins FROM users WHERE name=$1", username)
+func (s *UserService) AuthenticateUser(username, password string) (string, error) {
+	user, err := s.store.Users().GetUserByName(username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", ErrUnauthorized
+	}
 	if err != nil {
-		return "", errors.New("неавторизован")
+		return "", fmt.Errorf("error fetching user: %w", err)
 	}
 
 	// Проверяем пароль
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", errors.New("неавторизован")
+		return "", ErrUnauthorized
 	}
 
 	// Генерируем JWT-токен
-	token, err := utils.GenerateJWT(user.Username)
+	token, err := utils.GenerateJWT(user.Username, user.Role)
 	if err != nil {
-		return "", errors.New("внутренняя ошибка сервера")
+		return "", fmt.Errorf("внутренняя ошибка сервера: %w", err)
 	}
 
 	return token, nil
@@ -51,6 +91,7 @@ type UserInfo struct {
 	Coins       int                                 `json:"coins"`
 	Inventory   []UserItem                          `json:"inventory"`
 	CoinHistory map[string][]map[string]interface{} `json:"coinHistory"`
+	Orders      []models.Order                      `json:"orders"`
 }
 
 type UserItem struct {
@@ -58,23 +99,58 @@ type UserItem struct {
 	Amount   int    `db:"amount"`
 }
 
-func GetUserInfo(username string) (UserInfo, error) {
-	var user models.User
-	err := repositories.DB.Get(&user, "SELECT id, name, coins FROM users WHERE name=$1", username)
+// GetUserInfo - баланс, инвентарь, история переводов и заказы пользователя.
+// При groupHistory история переводов сводится к итогам по каждому участнику.
+func (s *UserService) GetUserInfo(username string, groupHistory bool) (UserInfo, error) {
+	user, err := findUser(s.store.Users(), username)
 	if err != nil {
-		return UserInfo{}, fmt.Errorf("error fetching user: %w", err)
+		return UserInfo{}, err
 	}
 
-	var inventory []UserItem
-	err = repositories.DB.Select(&inventory, "SELECT item_name, amount FROM inventory WHERE user_id=$1", user.ID)
+	rows, err := s.store.Inventory().ListInventory(user.ID)
 	if err != nil {
 		return UserInfo{}, fmt.Errorf("error fetching inventory: %w", err)
 	}
 
-	var transactions []models.Transaction
-	err = repositories.DB.Select(&transactions, "SELECT from_user, to_user, amount FROM transactions WHERE from_user=$1 OR to_user=$1", username)
+	// Вариант товара показываем рядом с названием: "hoody (L, black)"
+	inventory := make([]UserItem, 0, len(rows))
+	for _, row := range rows {
+		name := row.ItemName
+		if row.Variant != "" {
+			name += " (" + row.Variant + ")"
+		}
+		inventory = append(inventory, UserItem{ItemName: name, Amount: row.Amount})
+	}
+
+	var coinHistory map[string][]map[string]interface{}
+	if groupHistory {
+		coinHistory, err = s.groupedCoinHistory(username)
+	} else {
+		coinHistory, err = s.detailedCoinHistory(username)
+	}
 	if err != nil {
-		return UserInfo{}, fmt.Errorf("error fetching transactions: %w", err)
+		return UserInfo{}, err
+	}
+
+	// Заказы с текущим статусом выдачи
+	orders, err := s.store.Orders().ListUserOrders(user.ID)
+	if err != nil {
+		return UserInfo{}, fmt.Errorf("error fetching orders: %w", err)
+	}
+
+	return UserInfo{
+		Coins:       user.Coins,
+		Inventory:   inventory,
+		CoinHistory: coinHistory,
+		Orders:      orders,
+	}, nil
+}
+
+// detailedCoinHistory - история переводов по одной записи на перевод
+func (s *UserService) detailedCoinHistory(username string) (map[string][]map[string]interface{}, error) {
+	transactions, err := s.store.Transactions().ListUserTransactions(username)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching transactions: %w", err)
 	}
 
 	coinHistory := map[string][]map[string]interface{}{
@@ -83,16 +159,41 @@ func GetUserInfo(username string) (UserInfo, error) {
 	}
 
 	for _, t := range transactions {
+		entry := map[string]interface{}{"id": t.ID, "amount": t.Amount, "createdAt": t.CreatedAt}
+		if t.Message != "" {
+			entry["message"] = t.Message
+		}
 		if t.ToUser == username {
-			coinHistory["received"] = append(coinHistory["received"], map[string]interface{}{"fromUser": t.FromUser, "amount": t.Amount})
+			entry["fromUser"] = t.FromUser
+			coinHistory["received"] = append(coinHistory["received"], entry)
 		} else {
-			coinHistory["sent"] = append(coinHistory["sent"], map[string]interface{}{"toUser": t.ToUser, "amount": t.Amount})
+			entry["toUser"] = t.ToUser
+			coinHistory["sent"] = append(coinHistory["sent"], entry)
 		}
 	}
 
-	return UserInfo{
-		Coins:       user.Coins,
-		Inventory:   inventory,
-		CoinHistory: coinHistory,
-	}, nil
+	return coinHistory, nil
+}
+
+// groupedCoinHistory - история переводов, сгруппированная по участникам: сумма и количество переводов
+func (s *UserService) groupedCoinHistory(username string) (map[string][]map[string]interface{}, error) {
+	totals, err := s.store.Transactions().SumTransactionsByCounterparty(username)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching transaction totals: %w", err)
+	}
+
+	coinHistory := map[string][]map[string]interface{}{
+		"received": {},
+		"sent":     {},
+	}
+
+	for _, t := range totals {
+		if t.Direction == "received" {
+			coinHistory["received"] = append(coinHistory["received"], map[string]interface{}{"fromUser": t.Counterparty, "amount": t.Total, "count": t.Count})
+		} else {
+			coinHistory["sent"] = append(coinHistory["sent"], map[string]interface{}{"toUser": t.Counterparty, "amount": t.Total, "count": t.Count})
+		}
+	}
+
+	return coinHistory, nil
 }
@@ -7,7 +7,6 @@ import (
 	"github.com/stretchr/testify/assert"
 	"merch-store/handlers"
 	"merch-store/middlewares"
-	"merch-store/models"
 	"merch-store/repositories"
 	"net/http"
 	"net/http/httptest"
@@ -16,205 +15,182 @@ import (
 
 // Тестирую регистрацию
 func TestRegister(t *testing.T) {
-	// Инициализируем базу данных
-	repositories.InitTestDB()
-	// Очищаем тестовую таблицу пользователей
-	repositories.DB.Exec("DELETE FROM users")
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
 
-	// Регистрируем пользователя
-	registerUser(t, "testuser", "password123")
+		// Регистрируем пользователя
+		registerUser(t, h, "testuser", "password123")
 
-	// Проверяем, что пользователь был добавлен в базу
-	var user models.User
-	err := repositories.DB.Get(&user, "SELECT name FROM users WHERE name=$1", "testuser")
-	assert.Nil(t, err)
-	assert.Equal(t, "testuser", user.Username)
-
-	repositories.DB.Exec("DELETE FROM users")
+		// Проверяем, что пользователь был добавлен в базу
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		assert.Equal(t, "testuser", user.Username)
+	})
 }
 
 // Тестирую авторизацию
 func TestAuth(t *testing.T) {
-	// Инициализируем базу данных
-	repositories.InitTestDB()
-	// Очищаем тестовую таблицу пользователей
-	repositories.DB.Exec("DELETE FROM users")
-
-	// Регистрируем пользователя
-	registerUser(t, "testuser", "password123")
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
 
-	// Авторизуем пользователя
-	token := authUser(t, "testuser", "password123")
+		// Регистрируем пользователя
+		registerUser(t, h, "testuser", "password123")
 
-	// Проверяем, что токен был возвращен
-	assert.NotEmpty(t, token)
+		// Авторизуем пользователя
+		token := authUser(t, h, "testuser", "password123")
 
-	repositories.DB.Exec("DELETE FROM users")
+		// Проверяем, что токен был возвращен
+		assert.NotEmpty(t, token)
+	})
 }
 
 // Тестирую покупку мерча
 func TestBuyItem(t *testing.T) {
-	// Инициализируем базу данных
-	repositories.InitTestDB()
-	// Очищаем тестовую таблицу пользователей и инвентаря
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM inventory")
-
-	// Регистрируем пользователя
-	registerUser(t, "testuser", "password123")
-
-	// Авторизуем пользователя
-	token := authUser(t, "testuser", "password123")
-
-	// Подготовка запроса на покупку товара
-	buyRequest := map[string]int{
-		"amount": 1,
-	}
-	buyRequestBody, _ := json.Marshal(buyRequest)
-	buyReq, _ := http.NewRequest("POST", "/api/buy/t-shirt", bytes.NewBuffer(buyRequestBody))
-	buyReq.Header.Set("Authorization", "Bearer "+token)
-	buyW := httptest.NewRecorder()
-
-	// Инициализация маршрутов с middleware
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	api := router.Group("/api")
-	api.Use(middlewares.AuthMiddleware())
-	{
-		api.POST("/buy/:item", handlers.BuyItem)
-	}
-
-	// Выполняем запрос на покупку товара
-	router.ServeHTTP(buyW, buyReq)
-
-	// Проверяем статус код
-	assert.Equal(t, http.StatusOK, buyW.Code)
-
-	// Проверяем, что товар был добавлен в инвентарь
-	var inventory []models.Inventory
-	err := repositories.DB.Select(&inventory, "SELECT item_name, amount FROM inventory WHERE user_id=(SELECT id FROM users WHERE name=$1)", "testuser")
-	assert.Nil(t, err)
-	assert.Equal(t, "t-shirt", inventory[0].ItemName)
-	assert.Equal(t, 1, inventory[0].Amount)
-
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM inventory")
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
+
+		// Регистрируем пользователя
+		registerUser(t, h, "testuser", "password123")
+
+		// Авторизуем пользователя
+		token := authUser(t, h, "testuser", "password123")
+
+		// Подготовка запроса на покупку товара
+		buyRequest := map[string]int{
+			"amount": 1,
+		}
+		buyRequestBody, _ := json.Marshal(buyRequest)
+		buyReq, _ := http.NewRequest("POST", "/api/buy/t-shirt", bytes.NewBuffer(buyRequestBody))
+		buyReq.Header.Set("Authorization", "Bearer "+token)
+		buyW := httptest.NewRecorder()
+
+		// Инициализация маршрутов с middleware
+		gin.SetMode(gin.TestMode)
+		router := gin.Default()
+		api := router.Group("/api")
+		api.Use(middlewares.AuthMiddleware())
+		{
+			api.POST("/buy/:item", h.BuyItem)
+		}
+
+		// Выполняем запрос на покупку товара
+		router.ServeHTTP(buyW, buyReq)
+
+		// Проверяем статус код
+		assert.Equal(t, http.StatusOK, buyW.Code)
+
+		// Проверяем, что товар был добавлен в инвентарь
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		inventory, err := store.Inventory().ListInventory(user.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "t-shirt", inventory[0].ItemName)
+		assert.Equal(t, 1, inventory[0].Amount)
+	})
 }
 
 // Тестирую перевод монет
 func TestSendCoin(t *testing.T) {
-	// Инициализируем базу данных
-	repositories.InitTestDB()
-	// Очищаем тестовую таблицу пользователей и транзакций
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM transactions")
-
-	// Регистрируем двух пользователей
-	registerUser(t, "sender", "password123")
-	registerUser(t, "receiver", "password123")
-
-	// Авторизуем отправителя
-	token := authUser(t, "sender", "password123")
-
-	// Подготовка запроса на перевод монет
-	sendCoinRequest := map[string]interface{}{
-		"toUser": "receiver",
-		"amount": 50,
-	}
-	sendCoinRequestBody, _ := json.Marshal(sendCoinRequest)
-	sendCoinReq, _ := http.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(sendCoinRequestBody))
-	sendCoinReq.Header.Set("Authorization", "Bearer "+token)
-	sendCoinW := httptest.NewRecorder()
-
-	// Инициализация маршрутов с middleware
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	api := router.Group("/api")
-	api.Use(middlewares.AuthMiddleware())
-	{
-		api.POST("/sendCoin", handlers.SendCoin)
-	}
-
-	// Выполняем запрос на перевод монет
-	router.ServeHTTP(sendCoinW, sendCoinReq)
-
-	// Проверяем статус код
-	assert.Equal(t, http.StatusOK, sendCoinW.Code)
-
-	// Проверяем, что монеты были переведены
-	var sender, receiver models.User
-	err := repositories.DB.Get(&sender, "SELECT coins FROM users WHERE name=$1", "sender")
-	assert.Nil(t, err)
-	assert.Equal(t, 950, sender.Coins)
-
-	err = repositories.DB.Get(&receiver, "SELECT coins FROM users WHERE name=$1", "receiver")
-	assert.Nil(t, err)
-	assert.Equal(t, 1050, receiver.Coins)
-
-	// Проверяем, что транзакция была записана
-	var transaction models.Transaction
-	err = repositories.DB.Get(&transaction, "SELECT from_user, to_user, amount FROM transactions WHERE from_user=$1 AND to_user=$2", "sender", "receiver")
-	assert.Nil(t, err)
-	assert.Equal(t, "sender", transaction.FromUser)
-	assert.Equal(t, "receiver", transaction.ToUser)
-	assert.Equal(t, 50, transaction.Amount)
-
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM transactions")
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
+
+		// Регистрируем двух пользователей
+		registerUser(t, h, "sender", "password123")
+		registerUser(t, h, "receiver", "password123")
+
+		// Авторизуем отправителя
+		token := authUser(t, h, "sender", "password123")
+
+		// Подготовка запроса на перевод монет
+		sendCoinRequest := map[string]interface{}{
+			"toUser": "receiver",
+			"amount": 50,
+		}
+		sendCoinRequestBody, _ := json.Marshal(sendCoinRequest)
+		sendCoinReq, _ := http.NewRequest("POST", "/api/sendCoin", bytes.NewBuffer(sendCoinRequestBody))
+		sendCoinReq.Header.Set("Authorization", "Bearer "+token)
+		sendCoinW := httptest.NewRecorder()
+
+		// Инициализация маршрутов с middleware
+		gin.SetMode(gin.TestMode)
+		router := gin.Default()
+		api := router.Group("/api")
+		api.Use(middlewares.AuthMiddleware())
+		{
+			api.POST("/sendCoin", h.SendCoin)
+		}
+
+		// Выполняем запрос на перевод монет
+		router.ServeHTTP(sendCoinW, sendCoinReq)
+
+		// Проверяем статус код
+		assert.Equal(t, http.StatusOK, sendCoinW.Code)
+
+		// Проверяем, что монеты были переведены
+		sender, err := store.Users().GetUserByName("sender")
+		assert.Nil(t, err)
+		assert.Equal(t, 950, sender.Coins)
+
+		receiver, err := store.Users().GetUserByName("receiver")
+		assert.Nil(t, err)
+		assert.Equal(t, 1050, receiver.Coins)
+
+		// Проверяем, что транзакция была записана
+		transactions, err := store.Transactions().ListUserTransactions("sender")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(transactions))
+		transaction := transactions[0]
+		assert.Equal(t, "sender", transaction.FromUser)
+		assert.Equal(t, "receiver", transaction.ToUser)
+		assert.Equal(t, 50, transaction.Amount)
+	})
 }
 
 // Тестирую информацию о пользователе
 func TestGetUserInfo(t *testing.T) {
-	// Инициализируем базу данных
-	repositories.InitTestDB()
-	// Очищаем тестовую таблицу пользователей и инвентаря
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM inventory")
-	repositories.DB.Exec("DELETE FROM transactions")
-
-	// Регистрируем пользователя
-	registerUser(t, "testuser", "password123")
-
-	// Авторизуем пользователя
-	token := authUser(t, "testuser", "password123")
-
-	// Подготовка запроса на получение информации о пользователе
-	getUserInfoReq, _ := http.NewRequest("GET", "/api/info", nil)
-	getUserInfoReq.Header.Set("Authorization", "Bearer "+token)
-	getUserInfoW := httptest.NewRecorder()
-
-	// Инициализация маршрутов с middleware
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	api := router.Group("/api")
-	api.Use(middlewares.AuthMiddleware())
-	{
-		api.GET("/info", handlers.GetUserInfo)
-	}
-
-	// Выполняем запрос на получение информации о пользователе
-	router.ServeHTTP(getUserInfoW, getUserInfoReq)
-
-	// Проверяем статус код
-	assert.Equal(t, http.StatusOK, getUserInfoW.Code)
-
-	// Проверяем, что информация о пользователе корректна
-	var response map[string]interface{}
-	json.Unmarshal(getUserInfoW.Body.Bytes(), &response)
-
-	assert.Equal(t, "Успешный ответ.", response["description"])
-	assert.Equal(t, 1000, int(response["schema"].(map[string]interface{})["coins"].(float64)))
-	assert.Empty(t, response["schema"].(map[string]interface{})["inventory"])
-	assert.Empty(t, response["schema"].(map[string]interface{})["coinHistory"].(map[string]interface{})["received"])
-	assert.Empty(t, response["schema"].(map[string]interface{})["coinHistory"].(map[string]interface{})["sent"])
-
-	repositories.DB.Exec("DELETE FROM users")
-	repositories.DB.Exec("DELETE FROM inventory")
-	repositories.DB.Exec("DELETE FROM transactions")
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
+
+		// Регистрируем пользователя
+		registerUser(t, h, "testuser", "password123")
+
+		// Авторизуем пользователя
+		token := authUser(t, h, "testuser", "password123")
+
+		// Подготовка запроса на получение информации о пользователе
+		getUserInfoReq, _ := http.NewRequest("GET", "/api/info", nil)
+		getUserInfoReq.Header.Set("Authorization", "Bearer "+token)
+		getUserInfoW := httptest.NewRecorder()
+
+		// Инициализация маршрутов с middleware
+		gin.SetMode(gin.TestMode)
+		router := gin.Default()
+		api := router.Group("/api")
+		api.Use(middlewares.AuthMiddleware())
+		{
+			api.GET("/info", h.GetUserInfo)
+		}
+
+		// Выполняем запрос на получение информации о пользователе
+		router.ServeHTTP(getUserInfoW, getUserInfoReq)
+
+		// Проверяем статус код
+		assert.Equal(t, http.StatusOK, getUserInfoW.Code)
+
+		// Проверяем, что информация о пользователе корректна
+		var response map[string]interface{}
+		json.Unmarshal(getUserInfoW.Body.Bytes(), &response)
+
+		assert.Equal(t, "Успешный ответ.", response["description"])
+		assert.Equal(t, 1000, int(response["schema"].(map[string]interface{})["coins"].(float64)))
+		assert.Empty(t, response["schema"].(map[string]interface{})["inventory"])
+		assert.Empty(t, response["schema"].(map[string]interface{})["coinHistory"].(map[string]interface{})["received"])
+		assert.Empty(t, response["schema"].(map[string]interface{})["coinHistory"].(map[string]interface{})["sent"])
+	})
 }
 
 // Вспомогательная функция для регистрации
-func registerUser(t *testing.T, username, password string) {
+func registerUser(t *testing.T, h *handlers.Handler, username, password string) {
 	registerRequest := map[string]string{
 		"username": username,
 		"password": password,
@@ -224,7 +200,7 @@ func registerUser(t *testing.T, username, password string) {
 	registerW := httptest.NewRecorder()
 
 	router := gin.Default()
-	router.POST("/api/register", handlers.Register)
+	router.POST("/api/register", h.Register)
 
 	// Выполняем запрос на регистрацию
 	router.ServeHTTP(registerW, registerReq)
@@ -232,7 +208,7 @@ func registerUser(t *testing.T, username, password string) {
 }
 
 // Вспомогательная функция для авторизации
-func authUser(t *testing.T, username, password string) string {
+func authUser(t *testing.T, h *handlers.Handler, username, password string) string {
 	authRequest := map[string]string{
 		"username": username,
 		"password": password,
@@ -242,7 +218,7 @@ func authUser(t *testing.T, username, password string) string {
 	authW := httptest.NewRecorder()
 
 	router := gin.Default()
-	router.POST("/api/auth", handlers.Auth)
+	router.POST("/api/auth", h.Auth)
 
 	// Выполняем запрос на авторизацию
 	router.ServeHTTP(authW, authReq)
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"merch-store/config"
+	"merch-store/handlers"
+	"merch-store/repositories"
+	"merch-store/services"
+	"path/filepath"
+	"testing"
+)
+
+// backend - хранилище, на котором прогоняются сценарии API
+type backend struct {
+	name string
+	open func(t *testing.T) repositories.Store
+}
+
+// backends - все реализации хранилища; сценарии должны проходить на каждой
+var backends = []backend{
+	{name: "postgres", open: openPostgres},
+	{name: "sqlite", open: openSqlite},
+	{name: "memory", open: openMemory},
+}
+
+// forEachBackend - запуск сценария отдельным подтестом на каждом хранилище
+func forEachBackend(t *testing.T, scenario func(t *testing.T, store repositories.Store)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			scenario(t, b.open(t))
+		})
+	}
+}
+
+// openPostgres - тестовая БД на порту 5430, очищается до и после сценария.
+// Без запущенной тестовой БД сценарий пропускается.
+func openPostgres(t *testing.T) repositories.Store {
+	store, err := repositories.InitTestDB()
+	if err != nil {
+		t.Skip("тестовая БД недоступна:", err)
+	}
+
+	clean := func() {
+		store.DB().Exec(`TRUNCATE users, transactions, inventory, items, item_variants, restocks, orders, refunds,
+			cart_items, idempotency_keys, ledger_entries, balance_adjustments RESTART IDENTITY CASCADE`)
+	}
+	clean()
+	if err := store.SeedItems(config.Default().Catalog); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		clean()
+		store.DB().Close()
+	})
+
+	return store
+}
+
+// openSqlite - новый файл SQLite во временном каталоге теста с каталогом по умолчанию
+func openSqlite(t *testing.T) repositories.Store {
+	store, err := repositories.OpenSqlite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.DB().Close() })
+
+	if err := store.SeedItems(config.Default().Catalog); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// openMemory - новое хранилище в памяти с каталогом по умолчанию
+func openMemory(t *testing.T) repositories.Store {
+	store := repositories.NewMemoryStore()
+	if err := store.SeedItems(config.Default().Catalog); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// newHandler - обработчики API поверх хранилища с настройками по умолчанию
+func newHandler(store repositories.Store) *handlers.Handler {
+	return handlers.New(services.New(store, services.DefaultOptions()))
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"merch-store/repositories"
+	"merch-store/services"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тестирую параллельные переводы: баланс не должен уйти в минус
+func TestSendCoinConcurrent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
+		coins := services.NewCoinService(store)
+
+		// Регистрируем отправителя и получателей
+		registerUser(t, h, "sender", "password123")
+		registerUser(t, h, "receiver1", "password123")
+		registerUser(t, h, "receiver2", "password123")
+
+		// 50 переводов по 100 монет при балансе 1000: успешными могут быть ровно 10
+		const transfers = 50
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < transfers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				receiver := "receiver1"
+				if i%2 == 0 {
+					receiver = "receiver2"
+				}
+				if _, err := coins.SendCoin("sender", receiver, 100, ""); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, succeeded)
+
+		// Проверяем, что баланс отправителя ровно 0, а монеты не появились из ниоткуда
+		total := 0
+		for _, name := range []string{"sender", "receiver1", "receiver2"} {
+			user, err := store.Users().GetUserByName(name)
+			assert.Nil(t, err)
+			if name == "sender" {
+				assert.Equal(t, 0, user.Coins)
+			}
+			total += user.Coins
+		}
+		assert.Equal(t, 3000, total)
+
+		sent, err := store.Transactions().ListTransactions(repositories.TransactionFilter{
+			Username: "sender", Direction: "sent", Limit: transfers,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 10, len(sent))
+	})
+}
+
+// Тестирую встречные переводы A->B и B->A: ни один не должен завершиться ошибкой
+// из-за взаимной блокировки, а сумма монет не меняется
+func TestSendCoinOpposingConcurrent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		h := newHandler(store)
+		coins := services.NewCoinService(store)
+
+		registerUser(t, h, "alice", "password123")
+		registerUser(t, h, "bob", "password123")
+
+		// Суммы малы, поэтому монет хватает на все переводы в любом порядке
+		const transfers = 40
+		var wg sync.WaitGroup
+		errs := make(chan error, transfers)
+		for i := 0; i < transfers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				from, to := "alice", "bob"
+				if i%2 == 0 {
+					from, to = to, from
+				}
+				if _, err := coins.SendCoin(from, to, 10, ""); err != nil {
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assert.Nil(t, err)
+		}
+
+		total := 0
+		for _, name := range []string{"alice", "bob"} {
+			user, err := store.Users().GetUserByName(name)
+			assert.Nil(t, err)
+			assert.Equal(t, 1000, user.Coins)
+			total += user.Coins
+		}
+		assert.Equal(t, 2000, total)
+	})
+}
+
+// Тестирую параллельное оформление одной корзины: оплачена она должна быть ровно один раз
+func TestCheckoutConcurrent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("buyer", "password123"))
+		_, err := svc.Carts.AddToCart("buyer", "cup", 2, "", "")
+		assert.Nil(t, err)
+		_, err = svc.Carts.AddToCart("buyer", "pen", 1, "", "")
+		assert.Nil(t, err)
+
+		const checkouts = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, checkouts)
+		for i := 0; i < checkouts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.Carts.Checkout("buyer")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.ErrorIs(t, err, services.ErrCartEmpty)
+		}
+		assert.Equal(t, 1, succeeded)
+
+		user, err := store.Users().GetUserByName("buyer")
+		assert.Nil(t, err)
+		assert.Equal(t, 950, user.Coins)
+		orders, err := svc.Orders.ListOrders("buyer")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(orders))
+
+		report, err := svc.Ledger.CheckLedger()
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent)
+	})
+}
+
+// Тестирую одновременные сверки с корректировкой: расхождение исправляется один раз
+func TestReconcileConcurrent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("testuser", "password123"))
+
+		// Баланс изменен в обход журнала
+		debited, err := store.Users().DebitCoins("testuser", 300)
+		assert.Nil(t, err)
+		assert.True(t, debited)
+
+		const runs = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, runs)
+		for i := 0; i < runs; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := svc.Reconciliation.Reconcile(true); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.Nil(t, err)
+		}
+
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		assert.Equal(t, 1000, user.Coins)
+
+		ledger, err := svc.Ledger.CheckLedger()
+		assert.Nil(t, err)
+		assert.True(t, ledger.Consistent)
+	})
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"merch-store/repositories"
+	"merch-store/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тестирую ключи идемпотентности: брошенный запрос не блокирует ключ навсегда,
+// а устаревшие ключи удаляются очисткой
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		idempotency := services.NewIdempotencyService(store.IdempotencyKeys(), time.Second, time.Second)
+
+		// Запрос зарезервировал ключ и не завершился, например из-за падения процесса
+		_, saved, err := idempotency.BeginIdempotentRequest("testuser", "key-1", "hash-1")
+		assert.Nil(t, err)
+		assert.Nil(t, saved)
+		_, _, err = idempotency.BeginIdempotentRequest("testuser", "key-1", "hash-1")
+		assert.ErrorIs(t, err, services.ErrIdempotencyKeyInProgress)
+
+		// Завершенный запрос по другому ключу
+		request, _, err := idempotency.BeginIdempotentRequest("testuser", "key-2", "hash-2")
+		assert.Nil(t, err)
+		assert.Nil(t, idempotency.CompleteIdempotentRequest(request, 200, []byte(`{}`)))
+
+		time.Sleep(1500 * time.Millisecond)
+
+		// После таймаута повтор занимает брошенный ключ заново
+		_, saved, err = idempotency.BeginIdempotentRequest("testuser", "key-1", "hash-1")
+		assert.Nil(t, err)
+		assert.Nil(t, saved)
+
+		// Очистка удаляет завершенный ключ старше ttl, заново занятый ключ остается
+		deleted, err := idempotency.CleanupIdempotencyKeys()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = store.IdempotencyKeys().GetIdempotencyKey("testuser", "key-2")
+		assert.ErrorIs(t, err, repositories.ErrNotFound)
+		_, err = store.IdempotencyKeys().GetIdempotencyKey("testuser", "key-1")
+		assert.Nil(t, err)
+	})
+}
+
+// Тестирую ключ, который занят заново после таймаута: операция, зафиксированная до падения процесса,
+// не выполняется повтором, а зависший обработчик не фиксирует операцию после того, как ключ занял повтор
+func TestIdempotencyKeyReclaimAfterCommit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		opts := services.DefaultOptions()
+		opts.IdempotencyTimeout = time.Second
+		svc := services.New(store, opts)
+		assert.Nil(t, svc.Users.RegisterUser("sender", "password123"))
+		assert.Nil(t, svc.Users.RegisterUser("receiver", "password123"))
+
+		// Перевод зафиксирован, но процесс упал до сохранения ответа
+		committed, _, err := svc.Idempotency.BeginIdempotentRequest("sender", "key-1", "hash-1")
+		assert.Nil(t, err)
+		_, err = svc.WithIdempotentRequest(committed).Coins.SendCoin("sender", "receiver", 100, "")
+		assert.Nil(t, err)
+
+		// Обработчик завис до начала операции
+		stalled, _, err := svc.Idempotency.BeginIdempotentRequest("sender", "key-2", "hash-2")
+		assert.Nil(t, err)
+
+		time.Sleep(1500 * time.Millisecond)
+
+		_, _, err = svc.Idempotency.BeginIdempotentRequest("sender", "key-1", "hash-1")
+		assert.ErrorIs(t, err, services.ErrIdempotencyKeyCommitted)
+
+		retry, saved, err := svc.Idempotency.BeginIdempotentRequest("sender", "key-2", "hash-2")
+		assert.Nil(t, err)
+		assert.Nil(t, saved)
+		if !assert.NotNil(t, retry) {
+			return
+		}
+
+		// Зависший обработчик проснулся: его операция откатывается, ответ не сохраняется
+		_, err = svc.WithIdempotentRequest(stalled).Coins.SendCoin("sender", "receiver", 100, "")
+		assert.ErrorIs(t, err, services.ErrIdempotencyKeyInProgress)
+		assert.Nil(t, svc.Idempotency.CompleteIdempotentRequest(stalled, 409, []byte(`{"code":"idempotency_key_in_progress"}`)))
+
+		_, err = svc.WithIdempotentRequest(retry).Coins.SendCoin("sender", "receiver", 100, "")
+		assert.Nil(t, err)
+		assert.Nil(t, svc.Idempotency.CompleteIdempotentRequest(retry, 200, []byte(`{}`)))
+
+		_, saved, err = svc.Idempotency.BeginIdempotentRequest("sender", "key-2", "hash-2")
+		assert.Nil(t, err)
+		if assert.NotNil(t, saved) {
+			assert.Equal(t, 200, *saved.StatusCode)
+		}
+
+		user, err := store.Users().GetUserByName("sender")
+		assert.Nil(t, err)
+		assert.Equal(t, 800, user.Coins)
+	})
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"merch-store/models"
+	"merch-store/repositories"
+	"merch-store/services"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Тестирую полный откат и повторное применение миграций
+func TestMigrationsDownAndUp(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		migrator, ok := store.(repositories.Migrator)
+		if !ok {
+			t.Skip("хранилище без миграций")
+		}
+
+		// При открытии хранилища применены все миграции
+		states, err := migrator.MigrationStatus()
+		assert.Nil(t, err)
+		assert.NotEmpty(t, states)
+		for _, state := range states {
+			assert.NotNil(t, state.AppliedAt, state.Name)
+		}
+
+		reverted, err := migrator.MigrateDown(len(states))
+		assert.Nil(t, err)
+		assert.Len(t, reverted, len(states))
+		assert.Equal(t, 1, reverted[len(reverted)-1].Version)
+
+		states, err = migrator.MigrationStatus()
+		assert.Nil(t, err)
+		for _, state := range states {
+			assert.Nil(t, state.AppliedAt, state.Name)
+		}
+
+		applied, err := migrator.MigrateUp()
+		assert.Nil(t, err)
+		assert.Len(t, applied, len(states))
+
+		applied, err = migrator.MigrateUp()
+		assert.Nil(t, err)
+		assert.Empty(t, applied)
+
+		// После повторного применения схема снова рабочая
+		assert.Nil(t, store.Users().CreateUser(&models.User{Username: "testuser", Password: "hash", Coins: 100}))
+	})
+}
+
+// Тестирую откат и повторное применение последней миграции на базе с данными:
+// входящий остаток пользователя, появившегося до журнала, удаляется и записывается заново
+func TestMigrationsDownAndUpPopulated(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		migrator, ok := store.(repositories.Migrator)
+		if !ok {
+			t.Skip("хранилище без миграций")
+		}
+
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("newuser", "password123"))
+		// Пользователь из базы, созданной до журнала проводок: баланс без проводок
+		assert.Nil(t, store.Users().CreateUser(&models.User{Username: "olduser", Password: "hash", Coins: 700}))
+
+		// Применение миграции входящих остатков переносит его баланс в журнал;
+		// откатываются она и следующая за ней миграция ключей идемпотентности
+		_, err := migrator.MigrateDown(2)
+		assert.Nil(t, err)
+		_, err = migrator.MigrateUp()
+		assert.Nil(t, err)
+		report, err := svc.Ledger.CheckLedger()
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent)
+
+		_, err = svc.Coins.SendCoin("olduser", "newuser", 200, "")
+		assert.Nil(t, err)
+		_, err = svc.Coins.SendCoin("newuser", "olduser", 50, "")
+		assert.Nil(t, err)
+
+		for i := 0; i < 2; i++ {
+			reverted, err := migrator.MigrateDown(2)
+			assert.Nil(t, err)
+			if assert.Len(t, reverted, 2) {
+				assert.Equal(t, "0007_opening_balances", reverted[1].String())
+			}
+			applied, err := migrator.MigrateUp()
+			assert.Nil(t, err)
+			assert.Len(t, applied, 2)
+
+			report, err := svc.Ledger.CheckLedger()
+			assert.Nil(t, err)
+			assert.True(t, report.Consistent)
+		}
+
+		// Балансы и журнал переводов не изменились
+		info, err := svc.Users.GetUserInfo("olduser", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 550, info.Coins)
+		info, err = svc.Users.GetUserInfo("newuser", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 1150, info.Coins)
+	})
+}
+
+// Тестирую, что миграции PostgreSQL применяются под advisory-блокировкой
+func TestPostgresMigrationsTakeAdvisoryLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	store := repositories.NewPostgresStore(sqlx.NewDb(db, "sqlmock"))
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Применены все миграции, кроме последней
+	rows := sqlmock.NewRows([]string{"version", "name", "applied_at"})
+	for version, name := range []string{"users_and_transfers", "catalog", "orders_and_cart", "idempotency_keys", "ledger",
+		"balance_adjustments", "opening_balances"} {
+		rows.AddRow(version+1, name, time.Now())
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, name, applied_at FROM schema_migrations ORDER BY version")).WillReturnRows(rows)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM schema_migrations WHERE version=$1")).
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS token")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)")).
+		WithArgs(8, "idempotency_commit").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := store.MigrateUp()
+	assert.Nil(t, err)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, "0008_idempotency_commit", applied[0].String())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"errors"
+	"merch-store/models"
+	"merch-store/repositories"
+	"merch-store/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тестирую откат: при ошибке внутри Atomic не сохраняется ни одно изменение
+func TestStoreAtomicRollback(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		user := models.User{Username: "testuser", Password: "hash", Coins: 1000}
+		assert.Nil(t, store.Users().CreateUser(&user))
+
+		failure := errors.New("failure")
+		err := store.Atomic(func(tx repositories.Store) error {
+			debited, err := tx.Users().DebitCoins("testuser", 300)
+			assert.Nil(t, err)
+			assert.True(t, debited)
+			assert.Nil(t, tx.Inventory().AddInventory(user.ID, "cup", "", 1))
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		saved, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		assert.Equal(t, 1000, saved.Coins)
+		inventory, err := store.Inventory().ListInventory(user.ID)
+		assert.Nil(t, err)
+		assert.Empty(t, inventory)
+	})
+}
+
+// Тестирую уникальность имен и запрет отрицательного баланса
+func TestStoreUserConstraints(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		assert.Nil(t, store.Users().CreateUser(&models.User{Username: "testuser", Password: "hash", Coins: 100}))
+		err := store.Users().CreateUser(&models.User{Username: "testuser", Password: "hash", Coins: 100})
+		assert.ErrorIs(t, err, repositories.ErrDuplicate)
+
+		debited, err := store.Users().DebitCoins("testuser", 101)
+		assert.Nil(t, err)
+		assert.False(t, debited)
+		debited, err = store.Users().DebitCoins("testuser", 100)
+		assert.Nil(t, err)
+		assert.True(t, debited)
+
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, user.Coins)
+	})
+}
+
+// Тестирую атомарность покупки: если товара нет на складе, монеты не списываются
+func TestBuyItemOutOfStockIsAtomic(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("testuser", "password123"))
+		_, err := svc.Items.CreateItem("sticker", 5, "", new(int))
+		assert.Nil(t, err)
+
+		_, err = svc.Items.BuyItem("testuser", "sticker", 1, "", "")
+		assert.ErrorIs(t, err, services.ErrOutOfStock)
+
+		info, err := svc.Users.GetUserInfo("testuser", false)
+		assert.Nil(t, err)
+		assert.Equal(t, 1000, info.Coins)
+		assert.Empty(t, info.Inventory)
+		assert.Empty(t, info.Orders)
+
+		report, err := svc.Ledger.CheckLedger()
+		assert.Nil(t, err)
+		assert.True(t, report.Consistent)
+	})
+}
+
+// Тестирую сверку: ожидаемый баланс берется из журнала, а корректировка приводит к нему users.coins,
+// поэтому после нее и сверка, и проверка журнала не находят расхождений
+func TestReconcileCorrectsBalances(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("testuser", "password123"))
+		assert.Nil(t, svc.Users.RegisterUser("other", "password123"))
+
+		// Изменение стартового начисления не делает существующих пользователей расходящимися
+		changed := services.New(store, services.Options{StartingCoins: 500, CancelWindow: time.Hour})
+		report, err := changed.Reconciliation.Reconcile(false)
+		assert.Nil(t, err)
+		assert.Empty(t, report.Mismatches)
+
+		// Баланс изменен в обход журнала
+		credited, err := store.Users().CreditCoins("testuser", 50)
+		assert.Nil(t, err)
+		assert.True(t, credited)
+
+		report, err = svc.Reconciliation.Reconcile(true)
+		assert.Nil(t, err)
+		assert.True(t, report.Adjusted)
+		if assert.Equal(t, 1, len(report.Mismatches)) {
+			assert.Equal(t, "testuser", report.Mismatches[0].Username)
+			assert.Equal(t, 1000, report.Mismatches[0].Ledger)
+			assert.Equal(t, 50, report.Mismatches[0].Difference)
+		}
+
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		assert.Equal(t, 1000, user.Coins)
+
+		report, err = svc.Reconciliation.Reconcile(false)
+		assert.Nil(t, err)
+		assert.Empty(t, report.Mismatches)
+
+		ledger, err := svc.Ledger.CheckLedger()
+		assert.Nil(t, err)
+		assert.True(t, ledger.Consistent)
+	})
+}
+
+// Тестирую отмену заказа, созданного до появления orders.item_id: заказ читается,
+// а остаток товара возвращается по названию
+func TestCancelLegacyOrderWithoutItemID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		svc := services.New(store, services.DefaultOptions())
+		assert.Nil(t, svc.Users.RegisterUser("testuser", "password123"))
+		stock := 4
+		_, err := svc.Items.CreateItem("sticker", 5, "", &stock)
+		assert.Nil(t, err)
+
+		user, err := store.Users().GetUserByName("testuser")
+		assert.Nil(t, err)
+		order := models.Order{UserID: user.ID, ItemName: "sticker", Quantity: 1, UnitPrice: 5, Total: 5}
+		assert.Nil(t, store.Orders().CreateOrder(&order))
+		assert.Nil(t, store.Inventory().AddInventory(user.ID, "sticker", "", 1))
+
+		orders, err := svc.Orders.ListOrders("testuser")
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(orders)) {
+			assert.Nil(t, orders[0].ItemID)
+		}
+
+		refund, err := svc.Orders.CancelOrder("testuser", order.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, refund.Amount)
+
+		item, err := store.Items().GetItem("sticker")
+		assert.Nil(t, err)
+		if assert.NotNil(t, item.Stock) {
+			assert.Equal(t, 5, *item.Stock)
+		}
+	})
+}
+
+// Тестирую, что несбалансированная проводка не записывается в журнал
+func TestPostLedgerEntriesRejectsUnbalanced(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store repositories.Store) {
+		err := store.Ledger().PostLedgerEntries(models.LedgerTransfer, "transaction:1",
+			models.Posting{Account: models.UserAccount("user1"), Delta: -100},
+			models.Posting{Account: models.UserAccount("user2"), Delta: 90})
+		assert.Error(t, err)
+
+		unbalanced, err := store.Ledger().ListUnbalancedReferences()
+		assert.Nil(t, err)
+		assert.Empty(t, unbalanced)
+	})
+}
@@ -6,9 +6,12 @@ import (
 	"time"
 )
 
-// Секретный ключ для подписи JWT
+// Секретный ключ для подписи JWT, задается из настроек при запуске
 var JwtSecret = []byte("super-secret-key")
 
+// JwtTTL - срок действия токена
+var JwtTTL = 24 * time.Hour
+
 // HashPassword - хеширование пароля
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -24,11 +27,12 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateJWT - создание JWT-токена
-func GenerateJWT(username string) (string, error) {
+// GenerateJWT - создание JWT-токена с именем и ролью пользователя
+func GenerateJWT(username, role string) (string, error) {
 	claims := jwt.MapClaims{
 		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Токен действует 24 часа
+		"role":     role,
+		"exp":      time.Now().Add(JwtTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -46,7 +46,7 @@ func TestCheckPasswordHash(t *testing.T) {
 
 func TestGenerateJWT(t *testing.T) {
 	username := "testuser"
-	tokenString, err := GenerateJWT(username)
+	tokenString, err := GenerateJWT(username, "admin")
 	if err != nil {
 		t.Fatalf("Failed to generate JWT: %v", err)
 	}
@@ -74,6 +74,11 @@ func TestGenerateJWT(t *testing.T) {
 		t.Fatalf("Expected username to be %s, got %v", username, claims["username"])
 	}
 
+	// Проверяем, что роль в claims совпадает с ожидаемой
+	if claims["role"] != "admin" {
+		t.Fatalf("Expected role to be admin, got %v", claims["role"])
+	}
+
 	// Проверяем, что токен имеет правильное время истечения
 	exp := int64(claims["exp"].(float64))
 	if time.Unix(exp, 0).Before(time.Now().Add(23*time.Hour)) || time.Unix(exp, 0).After(time.Now().Add(25*time.Hour)) {
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage - язык сообщений, если клиент не указал поддерживаемый язык
+const DefaultLanguage = "ru"
+
+// messageCatalogs - тексты ответов API по языкам, ключ - код ошибки или успешного ответа
+var messageCatalogs = map[string]map[string]string{
+	"ru": {
+		"invalid_request":   "Неверный запрос.",
+		"validation_error":  "Неверный запрос.",
+		"internal_error":    "Внутренняя ошибка сервера.",
+		"unauthorized":      "Неавторизован.",
+		"forbidden":         "Доступ запрещен.",
+		"invalid_max_price": "Некорректная максимальная цена.",
+
+		"recipient_required": "Получатель не указан.",
+		"item_required":      "Товар не указан.",
+
+		"user_not_found": "пользователь не найден",
+		"user_exists":    "пользователь уже существует",
+
+		"insufficient_funds":  "недостаточно монет",
+		"recipient_not_found": "получатель не найден",
+		"invalid_amount":      "сумма перевода должна быть положительной",
+		"self_transfer":       "нельзя переводить монеты самому себе",
+		"message_too_long":    "сообщение к переводу слишком длинное",
+
+		"invalid_direction":    "направление должно быть sent или received",
+		"invalid_cursor":       "некорректный курсор",
+		"invalid_date_range":   "некорректный период",
+		"invalid_limit":        "некорректный размер страницы",
+		"invalid_history_view": "coinHistory может быть только grouped",
+
+		"item_not_found":     "товар не найден",
+		"item_exists":        "товар уже существует",
+		"invalid_item_price": "цена товара должна быть положительной",
+		"invalid_sort":       "некорректная сортировка",
+		"invalid_quantity":   "количество должно быть от 1 до 1000",
+		"out_of_stock":       "товара нет в наличии",
+		"variant_required":   "необходимо выбрать вариант товара",
+		"variant_not_found":  "вариант товара не найден",
+		"variant_exists":     "вариант товара уже существует",
+
+		"order_not_found":           "заказ не найден",
+		"order_not_cancellable":     "заказ уже выдан или отменен",
+		"cancel_window_expired":     "срок отмены заказа истек",
+		"invalid_order_status":      "неизвестный статус заказа",
+		"invalid_status_transition": "недопустимый переход статуса заказа",
+
+		"cart_empty":          "корзина пуста",
+		"cart_item_not_found": "позиция корзины не найдена",
+		"cart_changed":        "корзина изменилась во время оформления, повторите запрос",
+
+		"idempotency_key_reused":      "ключ идемпотентности уже использован для другого запроса",
+		"idempotency_key_in_progress": "запрос с этим ключом идемпотентности еще выполняется",
+		"idempotency_key_committed":   "запрос с этим ключом идемпотентности уже выполнен, но его ответ не сохранен",
+
+		"ok":                   "Успешный ответ.",
+		"user_registered":      "Пользователь зарегистрирован.",
+		"coins_sent":           "Успешная передача монет.",
+		"item_purchased":       "Товар приобретен",
+		"cart_item_added":      "Товар добавлен в корзину.",
+		"cart_item_removed":    "Позиция удалена из корзины.",
+		"order_placed":         "Заказ оформлен.",
+		"order_cancelled":      "Заказ отменен.",
+		"order_status_updated": "Статус заказа обновлен.",
+		"item_created":         "Товар добавлен.",
+		"item_updated":         "Товар обновлен.",
+		"item_deactivated":     "Товар снят с продажи.",
+		"item_restocked":       "Склад пополнен.",
+		"variant_created":      "Вариант товара добавлен.",
+	},
+	"en": {
+		"invalid_request":   "Invalid request.",
+		"validation_error":  "Invalid request.",
+		"internal_error":    "Internal server error.",
+		"unauthorized":      "Unauthorized.",
+		"forbidden":         "Access denied.",
+		"invalid_max_price": "Invalid maximum price.",
+
+		"recipient_required": "Recipient is required.",
+		"item_required":      "Item is required.",
+
+		"user_not_found": "user not found",
+		"user_exists":    "user already exists",
+
+		"insufficient_funds":  "insufficient coins",
+		"recipient_not_found": "recipient not found",
+		"invalid_amount":      "amount must be positive",
+		"self_transfer":       "cannot send coins to yourself",
+		"message_too_long":    "transfer message is too long",
+
+		"invalid_direction":    "direction must be sent or received",
+		"invalid_cursor":       "invalid cursor",
+		"invalid_date_range":   "invalid date range",
+		"invalid_limit":        "invalid page size",
+		"invalid_history_view": "coinHistory can only be grouped",
+
+		"item_not_found":     "item not found",
+		"item_exists":        "item already exists",
+		"invalid_item_price": "item price must be positive",
+		"invalid_sort":       "invalid sort order",
+		"invalid_quantity":   "quantity must be between 1 and 1000",
+		"out_of_stock":       "item is out of stock",
+		"variant_required":   "an item variant must be selected",
+		"variant_not_found":  "item variant not found",
+		"variant_exists":     "item variant already exists",
+
+		"order_not_found":           "order not found",
+		"order_not_cancellable":     "order has already been delivered or cancelled",
+		"cancel_window_expired":     "order cancellation window has expired",
+		"invalid_order_status":      "unknown order status",
+		"invalid_status_transition": "invalid order status transition",
+
+		"cart_empty":          "cart is empty",
+		"cart_item_not_found": "cart item not found",
+		"cart_changed":        "cart changed during checkout, please retry",
+
+		"idempotency_key_reused":      "idempotency key has already been used for a different request",
+		"idempotency_key_in_progress": "a request with this idempotency key is still in progress",
+		"idempotency_key_committed":   "a request with this idempotency key has already been completed, but its response was not saved",
+
+		"ok":                   "Success.",
+		"user_registered":      "User registered.",
+		"coins_sent":           "Coins sent.",
+		"item_purchased":       "Item purchased",
+		"cart_item_added":      "Item added to cart.",
+		"cart_item_removed":    "Item removed from cart.",
+		"order_placed":         "Order placed.",
+		"order_cancelled":      "Order cancelled.",
+		"order_status_updated": "Order status updated.",
+		"item_created":         "Item created.",
+		"item_updated":         "Item updated.",
+		"item_deactivated":     "Item removed from sale.",
+		"item_restocked":       "Stock replenished.",
+		"variant_created":      "Item variant created.",
+	},
+}
+
+// Language - выбор языка сообщений по заголовку Accept-Language с учетом весов q,
+// для неподдерживаемых языков возвращается DefaultLanguage
+func Language(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			value, ok := strings.CutPrefix(strings.TrimSpace(param), "q=")
+			if !ok {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+		// "en-US" и "en" обслуживаются одним каталогом
+		lang, _, _ := strings.Cut(tag, "-")
+		candidates = append(candidates, candidate{lang: lang, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if c.q <= 0 {
+			break
+		}
+		if _, ok := messageCatalogs[c.lang]; ok {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// Message - текст ошибки или успешного ответа по коду на выбранном языке, fallback если кода нет в каталоге
+func Message(lang, code, fallback string) string {
+	if message, ok := messageCatalogs[lang][code]; ok {
+		return message
+	}
+	return fallback
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                        "ru",
+		"en":                      "en",
+		"en-US,en;q=0.9":          "en",
+		"de-DE,en;q=0.8,ru;q=0.5": "en",
+		"ru;q=0.3, en;q=0.7":      "en",
+		"fr":                      "ru",
+		"en;q=0":                  "ru",
+	}
+	for header, expected := range cases {
+		if lang := Language(header); lang != expected {
+			t.Errorf("Language(%q) = %q, expected %q", header, lang, expected)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if msg := Message("en", "insufficient_funds", ""); msg != "insufficient coins" {
+		t.Errorf("Expected English message, got %q", msg)
+	}
+	if msg := Message("en", "unknown_code", "fallback"); msg != "fallback" {
+		t.Errorf("Expected fallback for unknown code, got %q", msg)
+	}
+}
+
+func TestMessageCatalogsMatch(t *testing.T) {
+	for lang, catalog := range messageCatalogs {
+		for other, otherCatalog := range messageCatalogs {
+			for code := range catalog {
+				if _, ok := otherCatalog[code]; !ok {
+					t.Errorf("Message %q is in %s catalog but missing in %s", code, lang, other)
+				}
+			}
+		}
+	}
+}